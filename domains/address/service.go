@@ -117,10 +117,10 @@ func (s *service) GetAddressByID(ctx context.Context, addressID string) (res *Ad
 	}
 
 	userID := token.Claims.ID
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_USER_MANAGE)
 
 	var address Address
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).First(&address, "id = ?", addressID).Error; err != nil {
 			return nil, apierror.NewWarn(http.StatusNotFound, "Failed, address not found")
 		}
@@ -151,11 +151,11 @@ func (s *service) DeleteAddress(ctx context.Context, addressID string) error {
 	}
 
 	userID := token.Claims.ID
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_USER_MANAGE)
 
 	var address Address
 
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).First(&address, "id = ?", addressID).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return apierror.NewWarn(http.StatusNotFound, "Address not found")
@@ -186,10 +186,10 @@ func (s *service) UpdateAddress(ctx context.Context, input UpdateAddressReq, add
 		return Address{}, apierror.FromErr(err)
 	}
 	userID := token.Claims.ID
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_USER_MANAGE)
 
 	var address Address
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).First(&address, "id = ?", addressID).Error; err != nil {
 			return Address{}, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
 		}
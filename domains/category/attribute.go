@@ -0,0 +1,157 @@
+package category
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"gorm.io/gorm"
+)
+
+var attributeKodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// EffectiveAttributes returns the schema a product in categoryID has to follow:
+// the category's own attributes plus everything defined on its ancestors.
+func EffectiveAttributes(ctx context.Context, db *gorm.DB, categoryID uint) ([]Attribute, error) {
+	tree, err := LoadTree(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, crumb := range tree.Breadcrumbs(categoryID) {
+		ids = append(ids, uint(crumb.ID))
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var attributes []Attribute
+	if err := db.WithContext(ctx).
+		Where("id_category IN ?", ids).
+		Order("id ASC").
+		Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+
+	return attributes, nil
+}
+
+// ValidateAttributes checks values against schema and returns them normalized
+// to the strings stored in produk_attribute.
+func ValidateAttributes(schema []Attribute, values map[string]any) (map[string]string, error) {
+	var problems []string
+	res := make(map[string]string, len(values))
+
+	known := make(map[string]Attribute, len(schema))
+	for _, attr := range schema {
+		known[attr.Kode] = attr
+	}
+
+	for kode := range values {
+		if _, ok := known[kode]; !ok {
+			problems = append(problems, fmt.Sprintf("attribute %s is not defined for this category", kode))
+		}
+	}
+
+	for _, attr := range schema {
+		raw, ok := values[attr.Kode]
+		if !ok || raw == nil || raw == "" {
+			if attr.Wajib {
+				problems = append(problems, fmt.Sprintf("attribute %s is required", attr.Kode))
+			}
+			continue
+		}
+
+		value, err := normalizeAttribute(attr, raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("attribute %s %s", attr.Kode, err.Error()))
+			continue
+		}
+		res[attr.Kode] = value
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, apierror.NewWarn(http.StatusBadRequest, problems...)
+	}
+
+	return res, nil
+}
+
+func normalizeAttribute(attr Attribute, raw any) (string, error) {
+	switch attr.Tipe {
+	case ATTRIBUTE_NUMBER:
+		switch v := raw.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return "", fmt.Errorf("must be a number")
+			}
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return "", fmt.Errorf("must be a number")
+
+	case ATTRIBUTE_BOOLEAN:
+		switch v := raw.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return "", fmt.Errorf("must be true or false")
+			}
+			return strconv.FormatBool(b), nil
+		}
+		return "", fmt.Errorf("must be true or false")
+
+	case ATTRIBUTE_ENUM:
+		v, ok := raw.(string)
+		if !ok || !slices.Contains(attr.Options(), v) {
+			return "", fmt.Errorf("must be one of %s", strings.Join(attr.Options(), ", "))
+		}
+		return v, nil
+
+	case ATTRIBUTE_STRING:
+		v, ok := raw.(string)
+		if !ok {
+			return "", fmt.Errorf("must be a string")
+		}
+		v = strings.TrimSpace(v)
+		if len(v) > 255 {
+			return "", fmt.Errorf("must be at most 255 characters")
+		}
+		return v, nil
+	}
+
+	return "", fmt.Errorf("has an unknown type %s", attr.Tipe)
+}
+
+// Options decodes the allowed values of an enum attribute.
+func (a Attribute) Options() []string {
+	var opsi []string
+	if a.Opsi != "" {
+		_ = json.Unmarshal([]byte(a.Opsi), &opsi)
+	}
+	return opsi
+}
+
+func toAttributeRes(attr Attribute) AttributeRes {
+	return AttributeRes{
+		ID:         int(attr.ID),
+		IdCategory: int(attr.IdCategory),
+		Kode:       attr.Kode,
+		Nama:       attr.Nama,
+		Tipe:       attr.Tipe,
+		Opsi:       attr.Options(),
+		Wajib:      attr.Wajib,
+	}
+}
@@ -0,0 +1,21 @@
+package category
+
+// Attribute types a category schema can use
+const (
+	ATTRIBUTE_STRING  = "string"
+	ATTRIBUTE_NUMBER  = "number"
+	ATTRIBUTE_ENUM    = "enum"
+	ATTRIBUTE_BOOLEAN = "boolean"
+)
+
+const (
+	ErrParentNotFound      = "Parent category not found"
+	ErrCategoryCycle       = "A category can't be moved under itself or one of its subcategories"
+	ErrCategoryHasChildren = "Category still has subcategories"
+	ErrCategoryInUse       = "Category is used by %d product(s), pass reassign_to to move them to another category"
+	ErrReassignNotFound    = "Category to reassign products to not found"
+	ErrReassignToSelf      = "Products can't be reassigned to the category being deleted"
+	ErrAttributeNotFound   = "Attribute not found"
+	ErrAttributeKode       = "kode must start with a letter and only contain lowercase letters, digits and underscores"
+	ErrAttributeTaken      = "Attribute %s is already defined on this category, a parent or a subcategory"
+)
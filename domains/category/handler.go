@@ -16,6 +16,11 @@ type Handler interface {
 	GetCategoryByID(ctx *fiber.Ctx) error
 	DeleteCategory(ctx *fiber.Ctx) error
 	UpdateCategory(ctx *fiber.Ctx) error
+	GetCategoryTree(ctx *fiber.Ctx) error
+	GetAttributes(ctx *fiber.Ctx) error
+	AddAttribute(ctx *fiber.Ctx) error
+	UpdateAttribute(ctx *fiber.Ctx) error
+	DeleteAttribute(ctx *fiber.Ctx) error
 }
 
 type handler struct {
@@ -87,13 +92,20 @@ func (h *handler) DeleteCategory(ctx *fiber.Ctx) error {
 		respond.Error(ctx, fmt.Errorf("id is required"))
 		return nil
 	}
-	err := h.service.DeleteCategory(ctx.Context(), categoryID)
+
+	var input DeleteCategoryReq
+	if err := ctx.QueryParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	res, err := h.service.DeleteCategory(ctx.Context(), categoryID, input)
 	if err != nil {
 		respond.Error(ctx, err)
 		return nil
 	}
 
-	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", nil)
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
 	return nil
 }
 
@@ -125,3 +137,105 @@ func (h *handler) UpdateCategory(ctx *fiber.Ctx) error {
 	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", res)
 	return nil
 }
+
+func (h *handler) GetCategoryTree(ctx *fiber.Ctx) error {
+	res, err := h.service.GetCategoryTree(ctx.Context())
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) GetAttributes(ctx *fiber.Ctx) error {
+	categoryID := ctx.Params("id")
+	if categoryID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	res, err := h.service.GetAttributes(ctx.Context(), categoryID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) AddAttribute(ctx *fiber.Ctx) error {
+	categoryID := ctx.Params("id")
+	if categoryID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input AttributeReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AddAttribute(ctx.Context(), categoryID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) UpdateAttribute(ctx *fiber.Ctx) error {
+	categoryID := ctx.Params("id")
+	attributeID := ctx.Params("attribute_id")
+	if categoryID == "" || attributeID == "" {
+		respond.Error(ctx, fmt.Errorf("id and attribute_id are required"))
+		return nil
+	}
+
+	var input AttributeReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.UpdateAttribute(ctx.Context(), categoryID, attributeID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", res)
+	return nil
+}
+
+func (h *handler) DeleteAttribute(ctx *fiber.Ctx) error {
+	categoryID := ctx.Params("id")
+	attributeID := ctx.Params("attribute_id")
+	if categoryID == "" || attributeID == "" {
+		respond.Error(ctx, fmt.Errorf("id and attribute_id are required"))
+		return nil
+	}
+
+	if err := h.service.DeleteAttribute(ctx.Context(), categoryID, attributeID); err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", nil)
+	return nil
+}
@@ -5,6 +5,19 @@ import "time"
 type Category struct {
 	ID            uint      `gorm:"primaryKey;autoIncrement"`
 	NamaCategory  string    `json:"nama_category"`
+	ParentID      *uint     `json:"parent_id" gorm:"column:parent_id"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+type Attribute struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	IdCategory    uint      `gorm:"not null"`
+	Kode          string    `json:"kode"`
+	Nama          string    `json:"nama"`
+	Tipe          string    `json:"tipe"`
+	Opsi          string    `json:"opsi"`
+	Wajib         bool      `json:"wajib"`
 	CreatedAtDate time.Time `gorm:"autoCreateTime"`
 	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
 }
@@ -12,3 +25,7 @@ type Category struct {
 func (Category) TableName() string {
 	return "category"
 }
+
+func (Attribute) TableName() string {
+	return "category_attribute"
+}
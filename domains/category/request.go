@@ -2,4 +2,18 @@ package category
 
 type CategoryReq struct {
 	NamaCategory string `json:"nama_category" validate:"required"`
+	// Omitted keeps the current parent on update, 0 makes it a root category
+	ParentID *uint `json:"parent_id"`
+}
+
+type DeleteCategoryReq struct {
+	ReassignTo *uint `query:"reassign_to"`
+}
+
+type AttributeReq struct {
+	Kode  string   `json:"kode" validate:"required,max=64"`
+	Nama  string   `json:"nama" validate:"required"`
+	Tipe  string   `json:"tipe" validate:"required,oneof=string number enum boolean"`
+	Opsi  []string `json:"opsi" validate:"required_if=Tipe enum,dive,required"`
+	Wajib bool     `json:"wajib"`
 }
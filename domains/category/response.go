@@ -3,4 +3,26 @@ package category
 type CategoryRes struct {
 	ID           int    `json:"id"`
 	NamaCategory string `json:"nama_category"`
+	ParentID     *int   `json:"parent_id,omitempty"`
+}
+
+type CategoryTreeRes struct {
+	ID           int               `json:"id"`
+	NamaCategory string            `json:"nama_category"`
+	Children     []CategoryTreeRes `json:"children"`
+}
+
+type DeleteCategoryRes struct {
+	ProductCount int64 `json:"product_count"`
+	ReassignedTo *int  `json:"reassigned_to,omitempty"`
+}
+
+type AttributeRes struct {
+	ID         int      `json:"id"`
+	IdCategory int      `json:"id_category"`
+	Kode       string   `json:"kode"`
+	Nama       string   `json:"nama"`
+	Tipe       string   `json:"tipe"`
+	Opsi       []string `json:"opsi,omitempty"`
+	Wajib      bool     `json:"wajib"`
 }
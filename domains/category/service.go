@@ -2,22 +2,30 @@ package category
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service interface {
 	AddCategory(ctx context.Context, input CategoryReq) (res *Category, err error)
 	GetAllCategory(ctx context.Context) (res []CategoryRes, err error)
 	GetCategoryByID(ctx context.Context, categoryID string) (res *CategoryRes, err error)
-	DeleteCategory(ctx context.Context, categoryID string) error
+	DeleteCategory(ctx context.Context, categoryID string, input DeleteCategoryReq) (res *DeleteCategoryRes, err error)
 	UpdateCategory(ctx context.Context, input CategoryReq, categoryID string) (res *CategoryRes, err error)
+	GetCategoryTree(ctx context.Context) (res []CategoryTreeRes, err error)
+	GetAttributes(ctx context.Context, categoryID string) (res []AttributeRes, err error)
+	AddAttribute(ctx context.Context, categoryID string, input AttributeReq) (res *AttributeRes, err error)
+	UpdateAttribute(ctx context.Context, categoryID string, attributeID string, input AttributeReq) (res *AttributeRes, err error)
+	DeleteAttribute(ctx context.Context, categoryID string, attributeID string) error
 }
 
 type service struct {
@@ -39,6 +47,13 @@ func (s *service) AddCategory(ctx context.Context, input CategoryReq) (res *Cate
 		UpdatedAtDate: time.Now(),
 	}
 
+	if input.ParentID != nil && *input.ParentID != 0 {
+		if err := s.db.WithContext(ctx).First(&Category{}, "id = ?", *input.ParentID).Error; err != nil {
+			return nil, apierror.NewWarn(http.StatusBadRequest, ErrParentNotFound)
+		}
+		category.ParentID = input.ParentID
+	}
+
 	// Insert into DB
 	if err := s.db.WithContext(ctx).Create(&category).Error; err != nil {
 		return nil, apierror.FromErr(err)
@@ -57,10 +72,7 @@ func (s *service) GetAllCategory(ctx context.Context) (res []CategoryRes, err er
 	var result []CategoryRes
 
 	for _, cat := range categories {
-		result = append(result, CategoryRes{
-			ID:           int(cat.ID),
-			NamaCategory: cat.NamaCategory,
-		})
+		result = append(result, toCategoryRes(cat))
 	}
 
 	return result, nil
@@ -73,28 +85,71 @@ func (s *service) GetCategoryByID(ctx context.Context, categoryID string) (res *
 		return nil, apierror.FromErr(err)
 	}
 
-	result := &CategoryRes{
-		ID:           int(category.ID),
-		NamaCategory: category.NamaCategory,
-	}
+	result := toCategoryRes(category)
 
-	return result, nil
+	return &result, nil
 }
 
-func (s *service) DeleteCategory(ctx context.Context, categoryID string) error {
-	var category Category
+// DeleteCategory refuses to drop a category products still point at,
+// unless ReassignTo names a category to move them to first.
+func (s *service) DeleteCategory(ctx context.Context, categoryID string, input DeleteCategoryReq) (res *DeleteCategoryRes, err error) {
+	res = &DeleteCategoryRes{}
 
-	if err := s.db.WithContext(ctx).First(&category, "id = ?", categoryID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return apierror.NewWarn(http.StatusNotFound, "Category not found")
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&category, "id = ?", categoryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, "Category not found")
+			}
+			return err
 		}
-		return apierror.FromErr(err)
-	}
-	if err := s.db.WithContext(ctx).Where("id = ?", categoryID).Delete(&Category{}).Error; err != nil {
-		return fmt.Errorf("error deleting product capital details: %v", err)
+
+		var children int64
+		if err := tx.Model(&Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return apierror.NewWarn(http.StatusConflict, ErrCategoryHasChildren)
+		}
+
+		// produk is owned by the product domain, which itself imports this package
+		if err := tx.Table("produk").Where("id_category = ?", category.ID).Count(&res.ProductCount).Error; err != nil {
+			return err
+		}
+
+		if res.ProductCount > 0 {
+			if input.ReassignTo == nil {
+				return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrCategoryInUse, res.ProductCount))
+			}
+			if *input.ReassignTo == category.ID {
+				return apierror.NewWarn(http.StatusBadRequest, ErrReassignToSelf)
+			}
+
+			var target Category
+			if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&target, "id = ?", *input.ReassignTo).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return apierror.NewWarn(http.StatusBadRequest, ErrReassignNotFound)
+				}
+				return err
+			}
+
+			if err := tx.Table("produk").
+				Where("id_category = ?", category.ID).
+				Updates(map[string]any{"id_category": target.ID, "updated_at_date": time.Now()}).Error; err != nil {
+				return err
+			}
+
+			reassignedTo := int(target.ID)
+			res.ReassignedTo = &reassignedTo
+		}
+
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
 	}
 
-	return nil
+	return res, nil
 }
 
 func (s *service) UpdateCategory(ctx context.Context, input CategoryReq, categoryID string) (res *CategoryRes, err error) {
@@ -104,6 +159,25 @@ func (s *service) UpdateCategory(ctx context.Context, input CategoryReq, categor
 		return nil, apierror.FromErr(err)
 	}
 
+	if input.ParentID != nil {
+		if *input.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			tree, err := LoadTree(ctx, s.db)
+			if err != nil {
+				return nil, apierror.FromErr(err)
+			}
+			if _, ok := tree.byID[*input.ParentID]; !ok {
+				return nil, apierror.NewWarn(http.StatusBadRequest, ErrParentNotFound)
+			}
+			// the new parent may not be the category itself or anything below it
+			if tree.IsDescendant(*input.ParentID, category.ID) {
+				return nil, apierror.NewWarn(http.StatusBadRequest, ErrCategoryCycle)
+			}
+			category.ParentID = input.ParentID
+		}
+	}
+
 	category.NamaCategory = input.NamaCategory
 	category.UpdatedAtDate = time.Now()
 
@@ -111,10 +185,133 @@ func (s *service) UpdateCategory(ctx context.Context, input CategoryReq, categor
 		return nil, apierror.FromErr(err)
 	}
 
-	result := &CategoryRes{
-		ID:           int(category.ID),
-		NamaCategory: input.NamaCategory,
+	result := toCategoryRes(category)
+
+	return &result, nil
+}
+
+func (s *service) GetCategoryTree(ctx context.Context) (res []CategoryTreeRes, err error) {
+	tree, err := LoadTree(ctx, s.db)
+	if err != nil {
+		return nil, apierror.FromErr(err)
 	}
 
-	return result, nil
+	return tree.Nodes(), nil
+}
+
+// GetAttributes lists the effective schema of a category, inherited attributes included.
+func (s *service) GetAttributes(ctx context.Context, categoryID string) (res []AttributeRes, err error) {
+	var category Category
+	if err := s.db.WithContext(ctx).First(&category, "id = ?", categoryID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Category not found")
+	}
+
+	attributes, err := EffectiveAttributes(ctx, s.db, category.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res = []AttributeRes{}
+	for _, attr := range attributes {
+		res = append(res, toAttributeRes(attr))
+	}
+
+	return res, nil
+}
+
+func (s *service) AddAttribute(ctx context.Context, categoryID string, input AttributeReq) (res *AttributeRes, err error) {
+	var category Category
+	if err := s.db.WithContext(ctx).First(&category, "id = ?", categoryID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Category not found")
+	}
+
+	attr := Attribute{
+		IdCategory:    category.ID,
+		CreatedAtDate: time.Now(),
+	}
+	if err := s.applyAttribute(ctx, &attr, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(&attr).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := toAttributeRes(attr)
+	return &result, nil
+}
+
+// UpdateAttribute changes the schema only, values already stored on products
+// are checked against it the next time those products are updated.
+func (s *service) UpdateAttribute(ctx context.Context, categoryID string, attributeID string, input AttributeReq) (res *AttributeRes, err error) {
+	var attr Attribute
+	if err := s.db.WithContext(ctx).First(&attr, "id = ? AND id_category = ?", attributeID, categoryID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrAttributeNotFound)
+	}
+
+	if err := s.applyAttribute(ctx, &attr, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(&attr).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := toAttributeRes(attr)
+	return &result, nil
+}
+
+func (s *service) DeleteAttribute(ctx context.Context, categoryID string, attributeID string) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND id_category = ?", attributeID, categoryID).Delete(&Attribute{})
+	if result.Error != nil {
+		return apierror.FromErr(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apierror.NewWarn(http.StatusNotFound, ErrAttributeNotFound)
+	}
+
+	return nil
+}
+
+func (s *service) applyAttribute(ctx context.Context, attr *Attribute, input AttributeReq) error {
+	kode := strings.ToLower(strings.TrimSpace(input.Kode))
+	if !attributeKodePattern.MatchString(kode) {
+		return apierror.NewWarn(http.StatusBadRequest, ErrAttributeKode)
+	}
+
+	// a product sees its whole ancestor chain, so a kode must be unique along it
+	tree, err := LoadTree(ctx, s.db)
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+	related := tree.Descendants(attr.IdCategory)
+	for _, crumb := range tree.Breadcrumbs(attr.IdCategory) {
+		related = append(related, uint(crumb.ID))
+	}
+
+	var taken int64
+	if err := s.db.WithContext(ctx).Model(&Attribute{}).
+		Where("id_category IN ? AND kode = ? AND id <> ?", related, kode, attr.ID).
+		Count(&taken).Error; err != nil {
+		return apierror.FromErr(err)
+	}
+	if taken > 0 {
+		return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrAttributeTaken, kode))
+	}
+
+	attr.Kode = kode
+	attr.Nama = input.Nama
+	attr.Tipe = input.Tipe
+	attr.Wajib = input.Wajib
+	attr.Opsi = ""
+	if input.Tipe == ATTRIBUTE_ENUM {
+		opsi, err := json.Marshal(input.Opsi)
+		if err != nil {
+			return apierror.FromErr(err)
+		}
+		attr.Opsi = string(opsi)
+	}
+	attr.UpdatedAtDate = time.Now()
+
+	return nil
 }
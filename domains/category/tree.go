@@ -0,0 +1,133 @@
+package category
+
+import (
+	"context"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Tree is an in-memory view of the whole category hierarchy. The category
+// table is small, so loading it once per request beats recursive queries.
+type Tree struct {
+	byID     map[uint]Category
+	children map[uint][]uint
+	roots    []uint
+}
+
+func LoadTree(ctx context.Context, db *gorm.DB) (*Tree, error) {
+	var categories []Category
+	if err := db.WithContext(ctx).Order("id ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &Tree{
+		byID:     make(map[uint]Category, len(categories)),
+		children: make(map[uint][]uint),
+	}
+	for _, cat := range categories {
+		tree.byID[cat.ID] = cat
+	}
+	for _, cat := range categories {
+		if cat.ParentID == nil {
+			tree.roots = append(tree.roots, cat.ID)
+			continue
+		}
+		if _, ok := tree.byID[*cat.ParentID]; !ok {
+			tree.roots = append(tree.roots, cat.ID)
+			continue
+		}
+		tree.children[*cat.ParentID] = append(tree.children[*cat.ParentID], cat.ID)
+	}
+
+	return tree, nil
+}
+
+// Descendants returns id itself followed by every category below it.
+func (t *Tree) Descendants(id uint) []uint {
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// IsDescendant reports whether id is ancestor itself or somewhere below it.
+func (t *Tree) IsDescendant(id, ancestor uint) bool {
+	seen := map[uint]bool{}
+	for cur := id; !seen[cur]; {
+		if cur == ancestor {
+			return true
+		}
+		seen[cur] = true
+
+		cat, ok := t.byID[cur]
+		if !ok || cat.ParentID == nil {
+			return false
+		}
+		cur = *cat.ParentID
+	}
+	return false
+}
+
+// Breadcrumbs lists the path from the root down to id.
+func (t *Tree) Breadcrumbs(id uint) []CategoryRes {
+	var path []CategoryRes
+	seen := map[uint]bool{}
+	for cur := id; !seen[cur]; {
+		cat, ok := t.byID[cur]
+		if !ok {
+			break
+		}
+		seen[cur] = true
+		path = append(path, toCategoryRes(cat))
+
+		if cat.ParentID == nil {
+			break
+		}
+		cur = *cat.ParentID
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func (t *Tree) Nodes() []CategoryTreeRes {
+	return t.nodes(t.roots)
+}
+
+func (t *Tree) nodes(ids []uint) []CategoryTreeRes {
+	res := []CategoryTreeRes{}
+	for _, id := range ids {
+		cat := t.byID[id]
+		res = append(res, CategoryTreeRes{
+			ID:           int(cat.ID),
+			NamaCategory: cat.NamaCategory,
+			Children:     t.nodes(t.children[id]),
+		})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].NamaCategory < res[j].NamaCategory
+	})
+	return res
+}
+
+func toCategoryRes(cat Category) CategoryRes {
+	res := CategoryRes{
+		ID:           int(cat.ID),
+		NamaCategory: cat.NamaCategory,
+	}
+	if cat.ParentID != nil {
+		parentID := int(*cat.ParentID)
+		res.ParentID = &parentID
+	}
+	return res
+}
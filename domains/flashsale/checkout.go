@@ -0,0 +1,120 @@
+package flashsale
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ActiveItem is a flash sale item together with the window of its campaign.
+type ActiveItem struct {
+	Item
+	Mulai   time.Time
+	Selesai time.Time
+}
+
+// ActiveItems returns, per product, the flash sale item that is live at the given time.
+func ActiveItems(db *gorm.DB, productIDs []uint, at time.Time) (map[uint]ActiveItem, error) {
+	res := map[uint]ActiveItem{}
+	if len(productIDs) == 0 {
+		return res, nil
+	}
+
+	var items []ActiveItem
+	if err := db.Table("flash_sale_item").
+		Select("flash_sale_item.*, flash_sale.mulai, flash_sale.selesai").
+		Joins("JOIN flash_sale ON flash_sale.id = flash_sale_item.id_flash_sale").
+		Where("flash_sale_item.id_produk IN ? AND flash_sale.mulai <= ? AND flash_sale.selesai > ?", productIDs, at, at).
+		Order("flash_sale.mulai DESC").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if _, ok := res[item.IdProduk]; !ok {
+			res[item.IdProduk] = item
+		}
+	}
+	return res, nil
+}
+
+// Reserve takes qty units of the item's quota for userID inside the checkout transaction.
+// The conditional UPDATE is what makes the quota safe under concurrent checkouts: it only
+// succeeds while enough quota is left, and holds the row lock until the order commits.
+func Reserve(tx *gorm.DB, item ActiveItem, userID, trxID uint, qty int) error {
+	result := tx.Model(&Item{}).
+		Where("id = ? AND terjual + ? <= kuota", item.ID, qty).
+		UpdateColumn("terjual", gorm.Expr("terjual + ?", qty))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrQuotaSoldOut, item.IdProduk))
+	}
+
+	// a locking read, so earlier purchases committed by a concurrent order are counted
+	var bought int
+	if err := tx.Model(&Purchase{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id_flash_sale_item = ? AND id_user = ?", item.ID, userID).
+		Select("COALESCE(SUM(kuantitas), 0)").
+		Scan(&bought).Error; err != nil {
+		return err
+	}
+	if bought+qty > item.BatasPerUser {
+		return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrUserLimit, item.BatasPerUser, item.IdProduk))
+	}
+
+	return tx.Create(&Purchase{
+		IdFlashSaleItem: item.ID,
+		IdUser:          userID,
+		IdTrx:           trxID,
+		Kuantitas:       qty,
+		CreatedAtDate:   time.Now(),
+		UpdatedAtDate:   time.Now(),
+	}).Error
+}
+
+// Release gives qty units reserved by an order back to the item's quota and drops them
+// from the buyer's purchases, for a line that was cancelled or returned.
+func Release(tx *gorm.DB, itemID, trxID uint, qty int) error {
+	if err := tx.Model(&Item{}).
+		Where("id = ?", itemID).
+		UpdateColumn("terjual", gorm.Expr("GREATEST(terjual - ?, 0)", qty)).Error; err != nil {
+		return err
+	}
+
+	var purchase Purchase
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id_flash_sale_item = ? AND id_trx = ?", itemID, trxID).
+		First(&purchase).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if purchase.Kuantitas <= qty {
+		return tx.Delete(&purchase).Error
+	}
+	return tx.Model(&purchase).UpdateColumn("kuantitas", gorm.Expr("kuantitas - ?", qty)).Error
+}
+
+func ToItemRes(item ActiveItem) ItemRes {
+	return ItemRes{
+		IdFlashSale:  int(item.IdFlashSale),
+		IdProduk:     int(item.IdProduk),
+		Harga:        item.Harga,
+		Kuota:        item.Kuota,
+		Sisa:         max(0, item.Kuota-item.Terjual),
+		BatasPerUser: item.BatasPerUser,
+		Mulai:        item.Mulai,
+		Selesai:      item.Selesai,
+	}
+}
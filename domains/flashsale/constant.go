@@ -0,0 +1,19 @@
+package flashsale
+
+const (
+	STATUS_SCHEDULED = "scheduled"
+	STATUS_LIVE      = "live"
+	STATUS_ENDED     = "ended"
+)
+
+const (
+	ErrFlashSaleNotFound = "Flash sale not found"
+	ErrFlashSaleRange    = "selesai must be after mulai and in the future"
+	ErrFlashSaleStarted  = "A flash sale that has already started can't be deleted"
+	ErrProductNotFound   = "Product %d not found"
+	ErrDuplicateProduct  = "Product %d is listed more than once"
+	ErrProductOverlap    = "Product %d is already in another flash sale during this time"
+	ErrInvalidItem       = "Product %d: batas_per_user can't exceed kuota"
+	ErrQuotaSoldOut      = "Flash sale quota for product %d is not enough for this order"
+	ErrUserLimit         = "You can buy at most %d of product %d in this flash sale"
+)
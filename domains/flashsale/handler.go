@@ -0,0 +1,99 @@
+package flashsale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/respond"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler interface {
+	AddFlashSale(ctx *fiber.Ctx) error
+	GetFlashSales(ctx *fiber.Ctx) error
+	GetFlashSaleByID(ctx *fiber.Ctx) error
+	DeleteFlashSale(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	service  Service
+	validate *validator.Validate
+}
+
+func NewHandler(service Service, validate *validator.Validate) Handler {
+	return &handler{
+		service:  service,
+		validate: validate,
+	}
+}
+
+func (h *handler) AddFlashSale(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input FlashSaleReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AddFlashSale(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) GetFlashSales(ctx *fiber.Ctx) error {
+	res, err := h.service.GetFlashSales(ctx.Context())
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) GetFlashSaleByID(ctx *fiber.Ctx) error {
+	flashSaleID := ctx.Params("id")
+	if flashSaleID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	res, err := h.service.GetFlashSaleByID(ctx.Context(), flashSaleID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) DeleteFlashSale(ctx *fiber.Ctx) error {
+	flashSaleID := ctx.Params("id")
+	if flashSaleID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	if err := h.service.DeleteFlashSale(ctx.Context(), flashSaleID); err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", nil)
+	return nil
+}
@@ -0,0 +1,48 @@
+package flashsale
+
+import "time"
+
+type FlashSale struct {
+	ID            uint      `gorm:"primaryKey"`
+	Nama          string    `json:"nama"`
+	Mulai         time.Time `json:"mulai"`
+	Selesai       time.Time `json:"selesai"`
+	IdUser        *uint     `json:"id_user"`
+	Items         []Item    `gorm:"foreignKey:IdFlashSale;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"items"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+type Item struct {
+	ID            uint      `gorm:"primaryKey"`
+	IdFlashSale   uint      `gorm:"not null"`
+	IdProduk      uint      `gorm:"not null"`
+	Harga         int       `json:"harga"`
+	Kuota         int       `json:"kuota"`
+	Terjual       int       `json:"terjual"`
+	BatasPerUser  int       `json:"batas_per_user"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+type Purchase struct {
+	ID              uint      `gorm:"primaryKey"`
+	IdFlashSaleItem uint      `gorm:"not null"`
+	IdUser          uint      `gorm:"not null"`
+	IdTrx           uint      `gorm:"not null"`
+	Kuantitas       int       `json:"kuantitas"`
+	CreatedAtDate   time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (FlashSale) TableName() string {
+	return "flash_sale"
+}
+
+func (Item) TableName() string {
+	return "flash_sale_item"
+}
+
+func (Purchase) TableName() string {
+	return "flash_sale_pembelian"
+}
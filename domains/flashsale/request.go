@@ -0,0 +1,17 @@
+package flashsale
+
+import "time"
+
+type FlashSaleReq struct {
+	Nama    string    `json:"nama" validate:"required"`
+	Mulai   time.Time `json:"mulai" validate:"required"`
+	Selesai time.Time `json:"selesai" validate:"required"`
+	Items   []ItemReq `json:"items" validate:"required,min=1,dive"`
+}
+
+type ItemReq struct {
+	IdProduk     uint `json:"product_id" validate:"required"`
+	Harga        int  `json:"harga" validate:"required,gt=0"`
+	Kuota        int  `json:"kuota" validate:"required,gt=0"`
+	BatasPerUser int  `json:"batas_per_user" validate:"required,gt=0"`
+}
@@ -0,0 +1,23 @@
+package flashsale
+
+import "time"
+
+type FlashSaleRes struct {
+	ID      int       `json:"id"`
+	Nama    string    `json:"nama"`
+	Mulai   time.Time `json:"mulai"`
+	Selesai time.Time `json:"selesai"`
+	Status  string    `json:"status"`
+	Items   []ItemRes `json:"items"`
+}
+
+type ItemRes struct {
+	IdFlashSale  int       `json:"flash_sale_id"`
+	IdProduk     int       `json:"product_id"`
+	Harga        int       `json:"harga"`
+	Kuota        int       `json:"kuota"`
+	Sisa         int       `json:"sisa_kuota"`
+	BatasPerUser int       `json:"batas_per_user"`
+	Mulai        time.Time `json:"mulai"`
+	Selesai      time.Time `json:"selesai"`
+}
@@ -0,0 +1,186 @@
+package flashsale
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	AddFlashSale(ctx context.Context, input FlashSaleReq) (res *FlashSaleRes, err error)
+	GetFlashSales(ctx context.Context) ([]FlashSaleRes, error)
+	GetFlashSaleByID(ctx context.Context, flashSaleID string) (res *FlashSaleRes, err error)
+	DeleteFlashSale(ctx context.Context, flashSaleID string) error
+}
+
+type service struct {
+	authConfig config.Auth
+	db         *gorm.DB
+}
+
+func NewService(config *config.Config, db *gorm.DB) Service {
+	return &service{
+		authConfig: config.Auth,
+		db:         db,
+	}
+}
+
+func (s *service) AddFlashSale(ctx context.Context, input FlashSaleReq) (res *FlashSaleRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	now := time.Now()
+	if !input.Selesai.After(input.Mulai) || !input.Selesai.After(now) {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrFlashSaleRange)
+	}
+
+	flashSale := FlashSale{
+		Nama:          input.Nama,
+		Mulai:         input.Mulai,
+		Selesai:       input.Selesai,
+		IdUser:        &actorID,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+
+	seen := map[uint]bool{}
+	for _, item := range input.Items {
+		if seen[item.IdProduk] {
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrDuplicateProduct, item.IdProduk))
+		}
+		seen[item.IdProduk] = true
+
+		if item.BatasPerUser > item.Kuota {
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrInvalidItem, item.IdProduk))
+		}
+
+		flashSale.Items = append(flashSale.Items, Item{
+			IdProduk:      item.IdProduk,
+			Harga:         item.Harga,
+			Kuota:         item.Kuota,
+			BatasPerUser:  item.BatasPerUser,
+			CreatedAtDate: now,
+			UpdatedAtDate: now,
+		})
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, item := range flashSale.Items {
+			// produk belongs to the product domain, which depends on this package
+			var found int64
+			if err := tx.Table("produk").Where("id = ?", item.IdProduk).Count(&found).Error; err != nil {
+				return err
+			}
+			if found == 0 {
+				return apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrProductNotFound, item.IdProduk))
+			}
+
+			var overlapping int64
+			if err := tx.Model(&Item{}).
+				Joins("JOIN flash_sale ON flash_sale.id = flash_sale_item.id_flash_sale").
+				Where("flash_sale_item.id_produk = ? AND flash_sale.mulai < ? AND flash_sale.selesai > ?", item.IdProduk, flashSale.Selesai, flashSale.Mulai).
+				Count(&overlapping).Error; err != nil {
+				return err
+			}
+			if overlapping > 0 {
+				return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrProductOverlap, item.IdProduk))
+			}
+		}
+
+		return tx.Create(&flashSale).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := toFlashSaleRes(flashSale, now)
+	return &result, nil
+}
+
+// GetFlashSales lists the campaigns that are live or still to come.
+func (s *service) GetFlashSales(ctx context.Context) ([]FlashSaleRes, error) {
+	now := time.Now()
+
+	var flashSales []FlashSale
+	if err := s.db.WithContext(ctx).
+		Preload("Items").
+		Where("selesai > ?", now).
+		Order("mulai ASC").
+		Find(&flashSales).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res := []FlashSaleRes{}
+	for _, fs := range flashSales {
+		res = append(res, toFlashSaleRes(fs, now))
+	}
+	return res, nil
+}
+
+func (s *service) GetFlashSaleByID(ctx context.Context, flashSaleID string) (res *FlashSaleRes, err error) {
+	var flashSale FlashSale
+	if err := s.db.WithContext(ctx).Preload("Items").First(&flashSale, "id = ?", flashSaleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusNotFound, ErrFlashSaleNotFound)
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	result := toFlashSaleRes(flashSale, time.Now())
+	return &result, nil
+}
+
+// DeleteFlashSale cancels a campaign before it goes live, started ones hold purchases.
+func (s *service) DeleteFlashSale(ctx context.Context, flashSaleID string) error {
+	var flashSale FlashSale
+	if err := s.db.WithContext(ctx).First(&flashSale, "id = ?", flashSaleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierror.NewWarn(http.StatusNotFound, ErrFlashSaleNotFound)
+		}
+		return apierror.FromErr(err)
+	}
+
+	if !flashSale.Mulai.After(time.Now()) {
+		return apierror.NewWarn(http.StatusConflict, ErrFlashSaleStarted)
+	}
+
+	if err := s.db.WithContext(ctx).Delete(&flashSale).Error; err != nil {
+		return apierror.FromErr(err)
+	}
+
+	return nil
+}
+
+func toFlashSaleRes(fs FlashSale, now time.Time) FlashSaleRes {
+	status := STATUS_LIVE
+	switch {
+	case fs.Mulai.After(now):
+		status = STATUS_SCHEDULED
+	case !fs.Selesai.After(now):
+		status = STATUS_ENDED
+	}
+
+	items := []ItemRes{}
+	for _, item := range fs.Items {
+		items = append(items, ToItemRes(ActiveItem{Item: item, Mulai: fs.Mulai, Selesai: fs.Selesai}))
+	}
+
+	return FlashSaleRes{
+		ID:      int(fs.ID),
+		Nama:    fs.Nama,
+		Mulai:   fs.Mulai,
+		Selesai: fs.Selesai,
+		Status:  status,
+		Items:   items,
+	}
+}
@@ -0,0 +1,13 @@
+package media
+
+// Every upload gets a fresh generated name, so the content behind a public key never changes
+const CACHE_CONTROL_IMMUTABLE = "public, max-age=31536000, immutable"
+
+// Keys under this prefix (e.g. return evidence) need a signed, expiring URL
+const PRIVATE_PREFIX = "private/"
+
+const (
+	ErrInvalidPath      = "Invalid media path"
+	ErrInvalidSignature = "Invalid or expired media signature"
+	ErrInvalidRange     = "Requested range not satisfiable"
+)
@@ -0,0 +1,212 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/respond"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler interface {
+	Serve(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	store  storage.Store
+	signer storage.Signer
+}
+
+func NewHandler(conf *config.Config, store storage.Store) Handler {
+	return &handler{
+		store:  store,
+		signer: storage.NewSigner(conf.Storage.SigningKey),
+	}
+}
+
+func (h *handler) Serve(ctx *fiber.Ctx) error {
+	rawKey, err := url.PathUnescape(ctx.Params("*"))
+	if err != nil {
+		respond.Error(ctx, apierror.NewWarn(http.StatusBadRequest, ErrInvalidPath))
+		return nil
+	}
+
+	key, err := storage.CleanKey(rawKey)
+	if err != nil {
+		respond.Error(ctx, apierror.NewWarn(http.StatusBadRequest, ErrInvalidPath))
+		return nil
+	}
+
+	cacheControl := CACHE_CONTROL_IMMUTABLE
+	if IsPrivate(key) {
+		expires := ctx.Query(storage.QUERY_PARAMS_EXPIRES)
+		if err := h.signer.Verify(key, expires, ctx.Query(storage.QUERY_PARAMS_SIGNATURE)); err != nil {
+			respond.Error(ctx, apierror.NewWarn(http.StatusForbidden, ErrInvalidSignature))
+			return nil
+		}
+
+		// never let a shared cache keep a private file beyond its signature
+		expiresAt, _ := strconv.ParseInt(expires, 10, 64)
+		cacheControl = fmt.Sprintf("private, max-age=%d", max(0, expiresAt-time.Now().Unix()))
+	}
+
+	obj, err := h.store.Get(ctx.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(ctx, apierror.FileNotFound())
+			return nil
+		}
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, cacheControl)
+	ctx.Set(fiber.HeaderAcceptRanges, "bytes")
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	ctx.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; sandbox")
+	if obj.ETag != "" {
+		ctx.Set(fiber.HeaderETag, obj.ETag)
+	}
+	if !obj.ModTime.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if obj.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, obj.ContentType)
+	}
+
+	if notModified(ctx, obj) {
+		obj.Body.Close()
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+
+	start, length, partial, ok := parseRange(ctx, obj)
+	if !ok {
+		obj.Body.Close()
+		ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", obj.Size))
+		respond.Error(ctx, apierror.NewWarn(http.StatusRequestedRangeNotSatisfiable, ErrInvalidRange))
+		return nil
+	}
+
+	if !partial {
+		ctx.Status(fiber.StatusOK)
+		return ctx.SendStream(obj.Body, int(obj.Size))
+	}
+
+	body, err := skip(obj.Body, start)
+	if err != nil {
+		obj.Body.Close()
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	ctx.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, obj.Size))
+	ctx.Status(fiber.StatusPartialContent)
+	return ctx.SendStream(readCloser{Reader: io.LimitReader(body, length), Closer: obj.Body}, int(length))
+}
+
+// IsPrivate reports whether key may only be served with a valid signature.
+func IsPrivate(key string) bool {
+	return strings.HasPrefix(key, PRIVATE_PREFIX)
+}
+
+func notModified(ctx *fiber.Ctx, obj *storage.Object) bool {
+	if match := ctx.Get(fiber.HeaderIfNoneMatch); match != "" {
+		if obj.ETag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == obj.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := ctx.Get(fiber.HeaderIfModifiedSince); since != "" && !obj.ModTime.IsZero() {
+		t, err := http.ParseTime(since)
+		return err == nil && !obj.ModTime.Truncate(time.Second).After(t)
+	}
+
+	return false
+}
+
+// parseRange supports a single "bytes=" range. Multi ranges and stale If-Range
+// fall back to the full body, ok is false only for an unsatisfiable range.
+func parseRange(ctx *fiber.Ctx, obj *storage.Object) (start, length int64, partial, ok bool) {
+	header := ctx.Get(fiber.HeaderRange)
+	if header == "" || obj.Size <= 0 {
+		return 0, obj.Size, false, true
+	}
+
+	if ifRange := ctx.Get(fiber.HeaderIfRange); ifRange != "" && ifRange != obj.ETag {
+		t, err := http.ParseTime(ifRange)
+		if err != nil || obj.ModTime.Truncate(time.Second).After(t) {
+			return 0, obj.Size, false, true
+		}
+	}
+
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, obj.Size, false, true
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, false
+	}
+
+	if first == "" {
+		// suffix range, e.g. bytes=-500 is the last 500 bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, false
+		}
+		n = min(n, obj.Size)
+		return obj.Size - n, n, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= obj.Size {
+		return 0, 0, false, false
+	}
+
+	end := obj.Size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, false
+		}
+		end = min(end, obj.Size-1)
+	}
+
+	return start, end - start + 1, true, true
+}
+
+func skip(body io.Reader, n int64) (io.Reader, error) {
+	if seeker, ok := body.(io.Seeker); ok {
+		if _, err := seeker.Seek(n, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return body, nil
+	}
+
+	if _, err := io.CopyN(io.Discard, body, n); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,137 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParseRange(t *testing.T) {
+	modTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	obj := &storage.Object{Size: 1000, ETag: `"abc"`, ModTime: modTime}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		empty   bool
+		start   int64
+		length  int64
+		partial bool
+		ok      bool
+	}{
+		{name: "no range", start: 0, length: 1000, ok: true},
+		{name: "closed range", header: http.Header{"Range": {"bytes=0-99"}}, start: 0, length: 100, partial: true, ok: true},
+		{name: "open range", header: http.Header{"Range": {"bytes=900-"}}, start: 900, length: 100, partial: true, ok: true},
+		{name: "end past size is clamped", header: http.Header{"Range": {"bytes=990-5000"}}, start: 990, length: 10, partial: true, ok: true},
+		{name: "suffix range", header: http.Header{"Range": {"bytes=-100"}}, start: 900, length: 100, partial: true, ok: true},
+		{name: "suffix larger than size", header: http.Header{"Range": {"bytes=-5000"}}, start: 0, length: 1000, partial: true, ok: true},
+		{name: "single byte", header: http.Header{"Range": {"bytes=999-999"}}, start: 999, length: 1, partial: true, ok: true},
+		{name: "multi range falls back to full body", header: http.Header{"Range": {"bytes=0-1,5-6"}}, start: 0, length: 1000, ok: true},
+		{name: "other unit falls back to full body", header: http.Header{"Range": {"items=0-1"}}, start: 0, length: 1000, ok: true},
+		{name: "start past size", header: http.Header{"Range": {"bytes=1000-"}}, ok: false},
+		{name: "end before start", header: http.Header{"Range": {"bytes=50-10"}}, ok: false},
+		{name: "empty suffix", header: http.Header{"Range": {"bytes=-0"}}, ok: false},
+		{name: "not a number", header: http.Header{"Range": {"bytes=a-b"}}, ok: false},
+		{name: "missing dash", header: http.Header{"Range": {"bytes=10"}}, ok: false},
+		{name: "empty object", header: http.Header{"Range": {"bytes=0-10"}}, empty: true, start: 0, length: 0, ok: true},
+		{name: "if-range matching etag", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"abc"`}}, start: 0, length: 10, partial: true, ok: true},
+		{name: "if-range stale etag", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {`"old"`}}, start: 0, length: 1000, ok: true},
+		{name: "if-range current date", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {modTime.Format(http.TimeFormat)}}, start: 0, length: 10, partial: true, ok: true},
+		{name: "if-range older date", header: http.Header{"Range": {"bytes=0-9"}, "If-Range": {modTime.Add(-time.Hour).Format(http.TimeFormat)}}, start: 0, length: 1000, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := *obj
+			if tt.empty {
+				o.Size = 0
+			}
+
+			app := fiber.New()
+			app.Get("/", func(ctx *fiber.Ctx) error {
+				start, length, partial, ok := parseRange(ctx, &o)
+				return ctx.SendString(fmt.Sprint(start, length, partial, ok))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := fmt.Sprint(tt.start, tt.length, tt.partial, tt.ok)
+			if string(body) != want {
+				t.Errorf("parseRange = %s, want %s", body, want)
+			}
+		})
+	}
+}
+
+func TestServePrivateNeedsSignature(t *testing.T) {
+	conf := &config.Config{}
+	conf.Storage.SigningKey = "secret"
+	store := storage.NewMemoryStore("/uploads", storage.NewSigner(conf.Storage.SigningKey))
+
+	ctx := context.Background()
+	for _, key := range []string{"products/a.jpg", "private/b.jpg"} {
+		if err := store.Put(ctx, key, []byte("data"), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := fiber.New()
+	app.Get("/uploads/*", NewHandler(conf, store).Serve)
+
+	signed, err := store.SignedURL("private/b.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigned, err := store.SignedURL("private/c.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherQuery, _ := strings.Cut(otherSigned, "?")
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"public key", "/uploads/products/a.jpg", http.StatusOK},
+		{"private key without signature", "/uploads/private/b.jpg", http.StatusForbidden},
+		{"private key with signature", signed, http.StatusOK},
+		{"private key with another key's signature", "/uploads/private/b.jpg?" + otherQuery, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package product
+
+// Kinds of log_produk rows
+const (
+	LOG_CHECKOUT = "checkout"
+	LOG_VERSION  = "version"
+)
+
+// Kinds of stock movements
+const (
+	STOCK_SALE         = "sale"
+	STOCK_CANCELLATION = "cancellation"
+	STOCK_RETURN       = "return"
+	STOCK_RESTOCK      = "restock"
+	STOCK_CORRECTION   = "correction"
+)
+
+const (
+	PRICE_RULE_SCHEDULED = "scheduled"
+	PRICE_RULE_ACTIVE    = "active"
+	PRICE_RULE_EXPIRED   = "expired"
+)
+
+const (
+	ErrPhotoNotFound        = "Photo not found"
+	ErrPhotoOrderIncomplete = "photo_ids must contain every photo of the product exactly once"
+	ErrInvalidAttributes    = "attributes must be a JSON object"
+	ErrVersionNotFound      = "Product version not found"
+	ErrVersionCategory      = "The category of this version no longer exists"
+	ErrVersionAttributes    = "The current attributes don't fit the category of this version: %s"
+	ErrPriceRuleNotFound    = "Price rule not found"
+	ErrPriceRuleRange       = "selesai must be after mulai and in the future"
+	ErrPriceRuleOverlap     = "Price rule overlaps with an existing rule of this product"
+	ErrStockInsufficient    = "Not enough stock for product %d"
+	ErrStockRestock         = "kuantitas of a restock must be greater than 0"
+	ErrImportFileRequired   = "file is required"
+	ErrImportEmpty          = "The file has no product rows"
+	ErrImportTooManyRows    = "The file has more than %d product rows"
+	ErrImportUnknownColumn  = "Unknown column %q"
+	ErrImportMissingColumn  = "Missing column %q"
+	ErrImportDuplicateID    = "Product %d appears in more than one row"
+	ErrImportNotOwned       = "Product %d not found in your shop"
+	ErrImportCategory       = "Category %d not found"
+)
+
+const (
+	IMPORT_MODE_DRY_RUN = "dry_run"
+	IMPORT_MODE_COMMIT  = "commit"
+
+	IMPORT_ACTION_CREATE = "create"
+	IMPORT_ACTION_UPDATE = "update"
+
+	MAX_IMPORT_ROWS = 1000
+	EXPORT_BATCH    = 200
+)
+
+// Columns of the import/export sheet, in export order. id is empty for new products.
+var ImportColumns = []string{
+	"id",
+	"nama_produk",
+	"slug",
+	"category_id",
+	"harga_reseller",
+	"harga_konsumen",
+	"stok",
+	"deskripsi",
+	"attributes",
+}
+
+// Query prefix for attribute filters on GetProducts, e.g. attr.ram_gb=8 or attr.screen_inch.min=6
+const QUERY_PARAMS_ATTRIBUTE_PREFIX = "attr."
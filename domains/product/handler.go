@@ -1,15 +1,18 @@
 package product
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
 	"github.com/devanadindraa/Evermos-Backend/utils/common"
 	"github.com/devanadindraa/Evermos-Backend/utils/respond"
+	sheetutils "github.com/devanadindraa/Evermos-Backend/utils/sheet"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,6 +23,19 @@ type Handler interface {
 	DeleteProduct(ctx *fiber.Ctx) error
 	UpdateProduct(ctx *fiber.Ctx) error
 	GetProducts(ctx *fiber.Ctx) error
+	AddPhoto(ctx *fiber.Ctx) error
+	DeletePhoto(ctx *fiber.Ctx) error
+	ReorderPhotos(ctx *fiber.Ctx) error
+	SetPrimaryPhoto(ctx *fiber.Ctx) error
+	GetProductHistory(ctx *fiber.Ctx) error
+	RevertProduct(ctx *fiber.Ctx) error
+	GetPriceRules(ctx *fiber.Ctx) error
+	AddPriceRule(ctx *fiber.Ctx) error
+	DeletePriceRule(ctx *fiber.Ctx) error
+	GetStockLedger(ctx *fiber.Ctx) error
+	AdjustStock(ctx *fiber.Ctx) error
+	ImportProducts(ctx *fiber.Ctx) error
+	ExportProducts(ctx *fiber.Ctx) error
 }
 
 type handler struct {
@@ -123,6 +139,9 @@ func (h *handler) UpdateProduct(ctx *fiber.Ctx) error {
 			input.IdCategory = &idCategory
 		}
 	}
+	if v := form.Value["attributes"]; len(v) > 0 {
+		input.Attributes = &v[0]
+	}
 	if v := form.Value["harga_reseller"]; len(v) > 0 {
 		input.HargaReseller = &v[0]
 	}
@@ -201,6 +220,13 @@ func (h *handler) GetProducts(ctx *fiber.Ctx) error {
 		}
 	}
 
+	attributes, err := parseAttributeFilters(ctx.Queries())
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+	req.Attributes = attributes
+
 	// Call service
 	res, err := h.service.GetProducts(reqCtx, req)
 	if err != nil {
@@ -211,3 +237,408 @@ func (h *handler) GetProducts(ctx *fiber.Ctx) error {
 	respond.Success(ctx, http.StatusOK, "Success", res)
 	return nil
 }
+
+func (h *handler) AddPhoto(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	file, err := ctx.FormFile("photo")
+	if err != nil {
+		respond.Error(ctx, apierror.NewWarn(http.StatusBadRequest, "photo is required"))
+		return nil
+	}
+
+	res, err := h.service.AddPhoto(reqCtx, productID, file)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) DeletePhoto(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	photoID := ctx.Params("photo_id")
+	if productID == "" || photoID == "" {
+		respond.Error(ctx, fmt.Errorf("id and photo_id are required"))
+		return nil
+	}
+
+	res, err := h.service.DeletePhoto(reqCtx, productID, photoID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) ReorderPhotos(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input ReorderPhotoReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.ReorderPhotos(reqCtx, productID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", res)
+	return nil
+}
+
+func (h *handler) SetPrimaryPhoto(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	photoID := ctx.Params("photo_id")
+	if productID == "" || photoID == "" {
+		respond.Error(ctx, fmt.Errorf("id and photo_id are required"))
+		return nil
+	}
+
+	res, err := h.service.SetPrimaryPhoto(reqCtx, productID, photoID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", res)
+	return nil
+}
+
+func parseAttributeFilters(queries map[string]string) ([]AttributeFilter, error) {
+	filters := map[string]*AttributeFilter{}
+	for key, value := range queries {
+		name, found := strings.CutPrefix(key, QUERY_PARAMS_ATTRIBUTE_PREFIX)
+		if !found || name == "" {
+			continue
+		}
+
+		kode, bound, _ := strings.Cut(name, ".")
+		filter, ok := filters[kode]
+		if !ok {
+			filter = &AttributeFilter{Kode: kode}
+			filters[kode] = filter
+		}
+
+		switch bound {
+		case "":
+			v := value
+			filter.Value = &v
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("%s must be a number", key))
+			}
+			if bound == "min" {
+				filter.Min = &n
+			} else {
+				filter.Max = &n
+			}
+		default:
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("Unknown attribute filter %s", key))
+		}
+	}
+
+	var res []AttributeFilter
+	for _, filter := range filters {
+		res = append(res, *filter)
+	}
+	return res, nil
+}
+
+func (h *handler) GetProductHistory(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	res, err := h.service.GetProductHistory(reqCtx, productID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) RevertProduct(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	versi := ctx.Params("versi")
+	if productID == "" || versi == "" {
+		respond.Error(ctx, fmt.Errorf("id and versi are required"))
+		return nil
+	}
+
+	res, err := h.service.RevertProduct(reqCtx, productID, versi)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) GetPriceRules(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	res, err := h.service.GetPriceRules(reqCtx, productID)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) AddPriceRule(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input PriceRuleReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AddPriceRule(reqCtx, productID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) DeletePriceRule(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	ruleID := ctx.Params("rule_id")
+	if productID == "" || ruleID == "" {
+		respond.Error(ctx, fmt.Errorf("id and rule_id are required"))
+		return nil
+	}
+
+	if err := h.service.DeletePriceRule(reqCtx, productID, ruleID); err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", nil)
+	return nil
+}
+
+func (h *handler) GetStockLedger(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	filter, err := common.GetMetaData(ctx, h.validate, "id", "created_at_date")
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	res, err := h.service.GetStockLedger(reqCtx, productID, filter)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) AdjustStock(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input StockAdjustmentReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AdjustStock(reqCtx, productID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) ImportProducts(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var req ImportProductReq
+	if err := ctx.BodyParser(&req); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		respond.Error(ctx, apierror.NewWarn(http.StatusBadRequest, ErrImportFileRequired))
+		return nil
+	}
+	req.File = file
+
+	format, err := sheetutils.FormatFromFilename(file.Filename)
+	if err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+	defer src.Close()
+
+	sheet, err := sheetutils.ReadAll(src, file.Size, format)
+	if err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	rows, err := parseImportRows(sheet)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	for i := range rows {
+		if err := h.validate.Struct(rows[i].Product); err != nil {
+			rows[i].Errors = append(rows[i].Errors, apierror.GetApiErrors(apierror.FromErr(err)).Messages...)
+		}
+	}
+
+	res, err := h.service.ImportProducts(reqCtx, rows, req.Mode == IMPORT_MODE_COMMIT)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	message := "Import checked, nothing was saved"
+	if res.Committed {
+		message = "Succeed to import products"
+	} else if res.Mode == IMPORT_MODE_COMMIT {
+		message = "Import has row errors, nothing was saved"
+	}
+
+	respond.Success(ctx, http.StatusOK, message, res)
+	return nil
+}
+
+func (h *handler) ExportProducts(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var req ExportProductReq
+	if err := ctx.QueryParser(&req); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	format := req.Format
+	if format == "" {
+		format = sheetutils.FORMAT_CSV
+	}
+
+	export, err := h.service.ExportProducts(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	ctx.Set("error", "")
+	ctx.Set("Content-Type", sheetutils.ContentType(format))
+	ctx.Set("Content-Disposition", fmt.Sprintf("attachment; filename=products.%s", format))
+	ctx.Status(http.StatusOK)
+
+	// headers are already sent once this runs, a failure can only cut the file short
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer, err := sheetutils.NewWriter(w, format)
+		if err != nil {
+			return
+		}
+		if err := writer.WriteRow(ImportColumns); err != nil {
+			return
+		}
+		if err := export(func(p Product) error {
+			return writer.WriteRow(exportCells(p))
+		}); err != nil {
+			return
+		}
+		writer.Close()
+	})
+	return nil
+}
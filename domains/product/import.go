@@ -0,0 +1,136 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+)
+
+// parseImportRows maps the sheet onto ProductReq by header name. Conversion problems are
+// kept per row so the whole file can be reported on at once.
+func parseImportRows(sheet [][]string) ([]ImportRow, error) {
+	if len(sheet) == 0 {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrImportEmpty)
+	}
+
+	known := map[string]bool{}
+	for _, col := range ImportColumns {
+		known[col] = true
+	}
+
+	columns := map[string]int{}
+	for i, name := range sheet[0] {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrImportUnknownColumn, name))
+		}
+		columns[name] = i
+	}
+	for _, col := range ImportColumns {
+		if _, ok := columns[col]; !ok && col != "id" && col != "slug" && col != "attributes" {
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrImportMissingColumn, col))
+		}
+	}
+
+	var rows []ImportRow
+	for i, cells := range sheet[1:] {
+		cell := func(name string) string {
+			idx, ok := columns[name]
+			if !ok || idx >= len(cells) {
+				return ""
+			}
+			return strings.TrimSpace(cells[idx])
+		}
+
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue
+		}
+		if len(rows) == MAX_IMPORT_ROWS {
+			return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf(ErrImportTooManyRows, MAX_IMPORT_ROWS))
+		}
+
+		// row numbers as the seller sees them in the sheet, the header being row 1
+		row := ImportRow{Row: i + 2}
+
+		if raw := cell("id"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil || id == 0 {
+				row.Errors = append(row.Errors, "id: must be a product id or empty")
+			} else {
+				productID := uint(id)
+				row.ID = &productID
+			}
+		}
+
+		row.Product = ProductReq{
+			NamaProduk:    cell("nama_produk"),
+			HargaReseller: cell("harga_reseller"),
+			HargaKonsumen: cell("harga_konsumen"),
+			Deskripsi:     cell("deskripsi"),
+			Attributes:    cell("attributes"),
+		}
+		if slug := cell("slug"); slug != "" {
+			row.Product.Slug = &slug
+		}
+
+		if raw := cell("category_id"); raw != "" {
+			categoryID, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				row.Errors = append(row.Errors, "category_id: must be a number")
+			}
+			row.Product.IdCategory = uint(categoryID)
+		}
+		if raw := cell("stok"); raw != "" {
+			stok, err := strconv.Atoi(raw)
+			if err != nil {
+				row.Errors = append(row.Errors, "stok: must be a number")
+			}
+			row.Product.Stok = stok
+		}
+		// prices are stored as text but checkout needs them to be whole numbers
+		for name, value := range map[string]string{"harga_reseller": row.Product.HargaReseller, "harga_konsumen": row.Product.HargaKonsumen} {
+			if _, err := strconv.Atoi(value); value != "" && err != nil {
+				row.Errors = append(row.Errors, name+": must be a whole number")
+			}
+		}
+		sort.Strings(row.Errors)
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrImportEmpty)
+	}
+	return rows, nil
+}
+
+// exportCells is the inverse of parseImportRows, an exported file can be imported back as is.
+func exportCells(p Product) []string {
+	attributes := ""
+	if values := ToAttributeMap(p.Attributes); len(values) > 0 {
+		// every stored value is a string, which ValidateAttributes accepts for any type
+		if raw, err := json.Marshal(values); err == nil {
+			attributes = string(raw)
+		}
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(p.ID), 10),
+		p.NamaProduk,
+		p.Slug,
+		strconv.FormatUint(uint64(p.IdCategory), 10),
+		p.HargaReseller,
+		p.HargaKonsumen,
+		strconv.Itoa(p.Stok),
+		p.Deskripsi,
+		attributes,
+	}
+}
@@ -2,31 +2,84 @@ package product
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Product struct {
+	ID            uint               `gorm:"primaryKey"`
+	IdToko        uint               `gorm:"not null"`
+	NamaProduk    string             `json:"nama_produk"`
+	IdCategory    uint               `gorm:"not null"`
+	Slug          string             `json:"slug"`
+	HargaReseller string             `json:"harga_reseller"`
+	HargaKonsumen string             `json:"harga_konsumen"`
+	Stok          int                `json:"stok"`
+	Deskripsi     string             `json:"deskripsi"`
+	Photos        []Photo            `gorm:"foreignKey:IdProduk;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"photos"`
+	Attributes    []ProductAttribute `gorm:"foreignKey:IdProduk;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"attributes"`
+	CreatedAtDate time.Time          `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time          `gorm:"autoUpdateTime"`
+}
+
+type Photo struct {
 	ID            uint      `gorm:"primaryKey"`
-	IdToko        uint      `gorm:"not null"`
-	NamaProduk    string    `json:"nama_produk"`
-	IdCategory    uint      `gorm:"not null"`
-	Slug          string    `json:"slug"`
-	HargaReseller string    `json:"harga_reseller"`
-	HargaKonsumen string    `json:"harga_konsumen"`
-	Stok          int       `json:"stok"`
-	Deskripsi     string    `json:"deskripsi"`
-	Photos        []Photo   `gorm:"foreignKey:IdProduk;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"photos"`
+	IdProduk      uint      `gorm:"not null"`
+	Url           string    `json:"url"`
+	UrlThumb      string    `json:"url_thumb"`
+	UrlMedium     string    `json:"url_medium"`
+	UrlLarge      string    `json:"url_large"`
+	Urutan        int       `json:"urutan"`
+	IsPrimary     bool      `json:"is_primary" gorm:"column:is_primary"`
 	CreatedAtDate time.Time `gorm:"autoCreateTime"`
 	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
 }
 
-type Photo struct {
+type ProductAttribute struct {
 	ID            uint      `gorm:"primaryKey"`
 	IdProduk      uint      `gorm:"not null"`
-	Url           string    `json:"url"`
+	Kode          string    `json:"kode"`
+	Nilai         string    `json:"nilai"`
 	CreatedAtDate time.Time `gorm:"autoCreateTime"`
 	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
 }
 
+// LogProduk is a snapshot of a product. Checkout rows freeze what was sold,
+// version rows (Versi set) record every edit made to the product.
+type LogProduk struct {
+	ID               uint      `gorm:"primaryKey"`
+	IdProduk         uint      `gorm:"not null"`
+	NamaProduk       string    `json:"nama_produk"`
+	Slug             string    `json:"slug"`
+	HargaReseller    string    `json:"harga_reseller"`
+	HargaKonsumen    string    `json:"harga_konsumen"`
+	Deskripsi        string    `json:"deskripsi"`
+	CreatedAtDate    time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate    time.Time `gorm:"autoUpdateTime"`
+	IdToko           uint      `gorm:"not null"`
+	IdCategory       *uint     `json:"id_category"`
+	Jenis            string    `json:"jenis"`
+	Versi            *int      `json:"versi"`
+	Stok             *int      `json:"stok"`
+	IdUser           *uint     `json:"id_user"`
+	Catatan          string    `json:"catatan"`
+	IdHargaTerjadwal *uint     `json:"id_harga_terjadwal"`
+	IdFlashSaleItem  *uint     `json:"id_flash_sale_item"`
+}
+
+// PriceRule overrides the product prices between Mulai and Selesai (open ended when nil).
+type PriceRule struct {
+	ID            uint       `gorm:"primaryKey"`
+	IdProduk      uint       `gorm:"not null"`
+	HargaKonsumen int        `json:"harga_konsumen"`
+	HargaReseller *int       `json:"harga_reseller"`
+	Mulai         time.Time  `json:"mulai"`
+	Selesai       *time.Time `json:"selesai"`
+	IdUser        *uint      `json:"id_user"`
+	CreatedAtDate time.Time  `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time  `gorm:"autoUpdateTime"`
+}
+
 func (Product) TableName() string {
 	return "produk"
 }
@@ -34,3 +87,38 @@ func (Product) TableName() string {
 func (Photo) TableName() string {
 	return "foto_produk"
 }
+
+func (ProductAttribute) TableName() string {
+	return "produk_attribute"
+}
+
+// StockMovement is one entry of the append-only stock ledger. Kuantitas is signed,
+// StokSetelah is the product stock right after the movement.
+type StockMovement struct {
+	ID            uint      `gorm:"primaryKey"`
+	IdProduk      uint      `gorm:"not null"`
+	Jenis         string    `json:"jenis"`
+	Kuantitas     int       `json:"kuantitas"`
+	StokSetelah   int       `json:"stok_setelah"`
+	Alasan        string    `json:"alasan"`
+	Referensi     string    `json:"referensi"`
+	IdUser        *uint     `json:"id_user"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+// OrderPhotos sorts photos the way they are shown: primary first, then by position.
+func OrderPhotos(db *gorm.DB) *gorm.DB {
+	return db.Order("is_primary DESC, urutan ASC, id ASC")
+}
+
+func (PriceRule) TableName() string {
+	return "harga_terjadwal"
+}
+
+func (LogProduk) TableName() string {
+	return "log_produk"
+}
+
+func (StockMovement) TableName() string {
+	return "mutasi_stok"
+}
@@ -2,6 +2,7 @@ package product
 
 import (
 	"mime/multipart"
+	"time"
 
 	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 )
@@ -15,6 +16,7 @@ type ProductReq struct {
 	Stok          int                     `form:"stok" validate:"required,min=0"`
 	Deskripsi     string                  `form:"deskripsi" validate:"required"`
 	Photos        []*multipart.FileHeader `form:"photos"`
+	Attributes    string                  `form:"attributes"`
 }
 
 type UpdateProductReq struct {
@@ -26,6 +28,42 @@ type UpdateProductReq struct {
 	Stok          *int                     `form:"stok"`
 	Deskripsi     *string                  `form:"deskripsi"`
 	Photos        *[]*multipart.FileHeader `form:"photos"`
+	Attributes    *string                  `form:"attributes"`
+}
+
+type ImportProductReq struct {
+	Mode string                `form:"mode" validate:"omitempty,oneof=dry_run commit"`
+	File *multipart.FileHeader `form:"file"`
+}
+
+// ImportRow is one sheet row converted to ProductReq, Errors holds what failed to parse or validate.
+type ImportRow struct {
+	Row     int
+	ID      *uint
+	Product ProductReq
+	Errors  []string
+}
+
+type ExportProductReq struct {
+	Format string `query:"format" validate:"omitempty,oneof=csv xlsx"`
+}
+
+// StockAdjustmentReq is a manual stock movement, Kuantitas is the signed change.
+type StockAdjustmentReq struct {
+	Jenis     string `json:"jenis" validate:"required,oneof=restock correction"`
+	Kuantitas int    `json:"kuantitas" validate:"required"`
+	Alasan    string `json:"alasan" validate:"required,max=255"`
+}
+
+type PriceRuleReq struct {
+	HargaKonsumen int        `json:"harga_konsumen" validate:"required,gt=0"`
+	HargaReseller *int       `json:"harga_reseller" validate:"omitempty,gt=0"`
+	Mulai         time.Time  `json:"mulai" validate:"required"`
+	Selesai       *time.Time `json:"selesai"`
+}
+
+type ReorderPhotoReq struct {
+	PhotoIDs []uint `json:"photo_ids" validate:"required,min=1"`
 }
 
 type GetProductReq struct {
@@ -34,4 +72,13 @@ type GetProductReq struct {
 	TokoID     *uint `query:"toko_id"`
 	MinHarga   *int  `query:"min_harga"`
 	MaxHarga   *int  `query:"max_harga"`
+	Attributes []AttributeFilter
+}
+
+// AttributeFilter comes from attr.<kode>=value, attr.<kode>.min and attr.<kode>.max
+type AttributeFilter struct {
+	Kode  string
+	Value *string
+	Min   *float64
+	Max   *float64
 }
@@ -1,20 +1,145 @@
 package product
 
 import (
+	"time"
+
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 )
 
 type ProductRes struct {
-	ID            int                   `json:"id"`
-	NamaProduk    *string               `json:"nama_produk,omitempty"`
-	Slug          *string               `json:"slug,omitempty"`
-	IdCategory    *uint                 `json:"category_id,omitempty"`
-	HargaReseller *string               `json:"harga_reseller,omitempty"`
-	HargaKonsumen *string               `json:"harga_konsumen,omitempty"`
-	Stok          *int                  `json:"stok,omitempty"`
-	Deskripsi     *string               `json:"deskripsi,omitempty"`
-	Shop          *shop.ShopRes         `json:"shop,omitempty"`
-	Category      *category.CategoryRes `json:"category,omitempty"`
-	Photos        []string              `json:"photos,omitempty"`
+	ID            int                    `json:"id"`
+	NamaProduk    *string                `json:"nama_produk,omitempty"`
+	Slug          *string                `json:"slug,omitempty"`
+	IdCategory    *uint                  `json:"category_id,omitempty"`
+	HargaReseller *string                `json:"harga_reseller,omitempty"`
+	HargaKonsumen *string                `json:"harga_konsumen,omitempty"`
+	HargaNormal   *string                `json:"harga_normal,omitempty"`
+	HargaSampai   *time.Time             `json:"harga_berlaku_sampai,omitempty"`
+	Stok          *int                   `json:"stok,omitempty"`
+	Deskripsi     *string                `json:"deskripsi,omitempty"`
+	Shop          *shop.ShopRes          `json:"shop,omitempty"`
+	Category      *category.CategoryRes  `json:"category,omitempty"`
+	Breadcrumbs   []category.CategoryRes `json:"breadcrumbs,omitempty"`
+	Photos        []PhotoRes             `json:"photos,omitempty"`
+	Attributes    map[string]string      `json:"attributes,omitempty"`
+	Rating        *shop.RatingRes        `json:"rating,omitempty"`
+	FlashSale     *flashsale.ItemRes     `json:"flash_sale,omitempty"`
+}
+
+type VersionRes struct {
+	Versi     int              `json:"versi"`
+	ChangedBy *VersionUserRes  `json:"changed_by,omitempty"`
+	Catatan   string           `json:"catatan,omitempty"`
+	Changes   []FieldChangeRes `json:"changes"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type VersionUserRes struct {
+	ID   int    `json:"id"`
+	Nama string `json:"nama"`
+}
+
+type FieldChangeRes struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type ImportRes struct {
+	Mode      string         `json:"mode"`
+	Committed bool           `json:"committed"`
+	Total     int            `json:"total"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Failed    int            `json:"failed"`
+	Rows      []ImportRowRes `json:"rows"`
+}
+
+type ImportRowRes struct {
+	Row    int      `json:"row"`
+	Action string   `json:"action"`
+	ID     *int     `json:"id,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// StockLedgerRes compares the stored stock with the balance of the ledger, Sesuai is
+// false when something changed the stock without recording a movement.
+type StockLedgerRes struct {
+	Stok       int                `json:"stok"`
+	StokLedger int                `json:"stok_ledger"`
+	Sesuai     bool               `json:"sesuai"`
+	Mutasi     []StockMovementRes `json:"mutasi"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+}
+
+type StockMovementRes struct {
+	ID          int             `json:"id"`
+	Jenis       string          `json:"jenis"`
+	Kuantitas   int             `json:"kuantitas"`
+	StokSetelah int             `json:"stok_setelah"`
+	Alasan      string          `json:"alasan,omitempty"`
+	Referensi   string          `json:"referensi,omitempty"`
+	User        *VersionUserRes `json:"user,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type PriceRuleRes struct {
+	ID            int        `json:"id"`
+	HargaKonsumen int        `json:"harga_konsumen"`
+	HargaReseller *int       `json:"harga_reseller,omitempty"`
+	Mulai         time.Time  `json:"mulai"`
+	Selesai       *time.Time `json:"selesai,omitempty"`
+	Status        string     `json:"status"`
+}
+
+type PhotoRes struct {
+	ID        int    `json:"id"`
+	Thumb     string `json:"thumb"`
+	Medium    string `json:"medium"`
+	Large     string `json:"large"`
+	Urutan    int    `json:"urutan"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// ToPhotoRes maps stored photos to their variant URLs.
+// Photos uploaded before variants existed only have Url, so it is used for every size.
+func ToPhotoRes(store storage.Store, photos []Photo) []PhotoRes {
+	var res []PhotoRes
+	for _, p := range photos {
+		photo := PhotoRes{
+			ID:        int(p.ID),
+			Thumb:     store.URL(p.UrlThumb),
+			Medium:    store.URL(p.UrlMedium),
+			Large:     store.URL(p.UrlLarge),
+			Urutan:    p.Urutan,
+			IsPrimary: p.IsPrimary,
+		}
+		if photo.Thumb == "" {
+			photo.Thumb = store.URL(p.Url)
+		}
+		if photo.Medium == "" {
+			photo.Medium = store.URL(p.Url)
+		}
+		if photo.Large == "" {
+			photo.Large = store.URL(p.Url)
+		}
+		res = append(res, photo)
+	}
+	return res
+}
+
+func ToAttributeMap(attributes []ProductAttribute) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(attributes))
+	for _, attr := range attributes {
+		res[attr.Kode] = attr.Nilai
+	}
+	return res
 }
@@ -2,22 +2,28 @@ package product
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
+	"github.com/devanadindraa/Evermos-Backend/domains/user"
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
 	fileutils "github.com/devanadindraa/Evermos-Backend/utils/file"
+	imageutils "github.com/devanadindraa/Evermos-Backend/utils/image"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service interface {
@@ -26,17 +32,32 @@ type Service interface {
 	DeleteProduct(ctx context.Context, productID string) error
 	UpdateProduct(ctx context.Context, input UpdateProductReq, IdToko string) (res *ProductRes, err error)
 	GetProducts(ctx context.Context, filter GetProductReq) ([]ProductRes, error)
+	AddPhoto(ctx context.Context, productID string, file *multipart.FileHeader) ([]PhotoRes, error)
+	DeletePhoto(ctx context.Context, productID string, photoID string) ([]PhotoRes, error)
+	ReorderPhotos(ctx context.Context, productID string, input ReorderPhotoReq) ([]PhotoRes, error)
+	SetPrimaryPhoto(ctx context.Context, productID string, photoID string) ([]PhotoRes, error)
+	GetProductHistory(ctx context.Context, productID string) ([]VersionRes, error)
+	RevertProduct(ctx context.Context, productID string, versi string) (res *ProductRes, err error)
+	GetPriceRules(ctx context.Context, productID string) ([]PriceRuleRes, error)
+	AddPriceRule(ctx context.Context, productID string, input PriceRuleReq) (res *PriceRuleRes, err error)
+	DeletePriceRule(ctx context.Context, productID string, ruleID string) error
+	GetStockLedger(ctx context.Context, productID string, filter *constants.FilterReq) (res *StockLedgerRes, err error)
+	AdjustStock(ctx context.Context, productID string, input StockAdjustmentReq) (res *StockMovementRes, err error)
+	ImportProducts(ctx context.Context, rows []ImportRow, commit bool) (res *ImportRes, err error)
+	ExportProducts(ctx context.Context) (func(write func(Product) error) error, error)
 }
 
 type service struct {
 	authConfig config.Auth
 	db         *gorm.DB
+	store      storage.Store
 }
 
-func NewService(config *config.Config, db *gorm.DB) Service {
+func NewService(config *config.Config, db *gorm.DB, store storage.Store) Service {
 	return &service{
 		authConfig: config.Auth,
 		db:         db,
+		store:      store,
 	}
 }
 
@@ -61,9 +82,22 @@ func (s *service) AddProduct(ctx context.Context, input ProductReq) (res *Produc
 
 	var shop shop.Toko
 	if err := s.db.WithContext(ctx).First(&shop, "id_user = ?", UserID).Error; err != nil {
+		tx.Rollback()
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
 	}
 
+	values, err := parseAttributes(input.Attributes)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	attributes, err := s.resolveAttributes(ctx, input.IdCategory, values)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	product := Product{
 		IdToko:     shop.ID,
 		NamaProduk: input.NamaProduk,
@@ -76,7 +110,6 @@ func (s *service) AddProduct(ctx context.Context, input ProductReq) (res *Produc
 		IdCategory:    input.IdCategory,
 		HargaReseller: input.HargaReseller,
 		HargaKonsumen: input.HargaKonsumen,
-		Stok:          input.Stok,
 		Deskripsi:     input.Deskripsi,
 		CreatedAtDate: time.Now(),
 		UpdatedAtDate: time.Now(),
@@ -87,40 +120,42 @@ func (s *service) AddProduct(ctx context.Context, input ProductReq) (res *Produc
 		return nil, err
 	}
 
-	var photos []Photo
-	for _, file := range input.Photos {
-		ext := filepath.Ext(file.Filename)
-
-		filename, err := fileutils.GenerateMediaName(strconv.Itoa(int(product.ID)))
+	actorID := uint(UserID)
+	if input.Stok > 0 {
+		movement, err := MoveStock(tx, StockChange{
+			ProductID: product.ID,
+			Jenis:     STOCK_RESTOCK,
+			Kuantitas: input.Stok,
+			Alasan:    "stok awal",
+			ActorID:   &actorID,
+		})
 		if err != nil {
 			tx.Rollback()
-			return nil, apierror.NewWarn(http.StatusNotFound, fmt.Sprintf("error generating image name: %v", err))
+			return nil, err
 		}
+		product.Stok = movement.StokSetelah
+	}
 
-		filename = fmt.Sprintf("%s%s", filename, ext)
-		path := filepath.Join("uploads", "products", filename)
-
-		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-			tx.Rollback()
-			return nil, apierror.NewWarn(http.StatusNotFound, fmt.Sprintf("failed to create directory: %v", err))
-		}
+	if err := saveAttributes(tx, product.ID, attributes); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-		if err := fileutils.SaveMedia(ctx, file, path); err != nil {
-			tx.Rollback()
-			return nil, err
-		}
+	if err := recordVersion(tx, product, &actorID, ""); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-		photos = append(photos, Photo{
-			IdProduk:      product.ID,
-			Url:           "/uploads/products/" + filename,
-			CreatedAtDate: time.Now(),
-			UpdatedAtDate: time.Now(),
-		})
+	photos, err := s.savePhotos(ctx, product.ID, input.Photos)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	if len(photos) > 0 {
 		if err := tx.Create(&photos).Error; err != nil {
 			tx.Rollback()
+			s.removePhotos(ctx, photos)
 			return nil, err
 		}
 	}
@@ -128,9 +163,11 @@ func (s *service) AddProduct(ctx context.Context, input ProductReq) (res *Produc
 	result := &ProductRes{
 		ID:         int(product.ID),
 		NamaProduk: &product.NamaProduk,
+		Photos:     ToPhotoRes(s.store, photos),
 	}
 
 	if err := tx.Commit().Error; err != nil {
+		s.removePhotos(ctx, photos)
 		return nil, err
 	}
 
@@ -140,7 +177,7 @@ func (s *service) AddProduct(ctx context.Context, input ProductReq) (res *Produc
 func (s *service) GetProductByID(ctx context.Context, productID string) (res *ProductRes, err error) {
 
 	var product Product
-	if err := s.db.WithContext(ctx).Preload("Photos").First(&product, "id = ?", productID).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Photos", OrderPhotos).Preload("Attributes").First(&product, "id = ?", productID).Error; err != nil {
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, product not found")
 	}
 
@@ -154,9 +191,30 @@ func (s *service) GetProductByID(ctx context.Context, productID string) (res *Pr
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, category not found")
 	}
 
-	var photoURLs []string
-	for _, p := range product.Photos {
-		photoURLs = append(photoURLs, p.Url)
+	tree, err := category.LoadTree(ctx, s.db)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	basePrice := product.HargaKonsumen
+	priceRule, err := ApplyEffectivePrice(s.db.WithContext(ctx), &product, time.Now())
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	flashItems, err := flashsale.ActiveItems(s.db.WithContext(ctx), []uint{product.ID}, time.Now())
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	rating, err := shop.GetRating(s.db.WithContext(ctx), shop.RATING_BY_PRODUCT, product.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	shopRating, err := shop.GetRating(s.db.WithContext(ctx), shop.RATING_BY_SHOP, shops.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
 	}
 
 	result := &ProductRes{
@@ -170,13 +228,25 @@ func (s *service) GetProductByID(ctx context.Context, productID string) (res *Pr
 		Shop: &shop.ShopRes{
 			ID:       int(shops.ID),
 			NamaToko: shops.NamaToko,
-			UrlFoto:  shops.UrlFoto,
+			UrlFoto:  s.store.URL(shops.UrlFoto),
+			Rating:   shopRating,
 		},
 		Category: &category.CategoryRes{
 			ID:           int(categorys.ID),
 			NamaCategory: categorys.NamaCategory,
 		},
-		Photos: photoURLs,
+		Breadcrumbs: tree.Breadcrumbs(categorys.ID),
+		Photos:      ToPhotoRes(s.store, product.Photos),
+		Attributes:  ToAttributeMap(product.Attributes),
+		Rating:      rating,
+	}
+	if priceRule != nil {
+		result.HargaNormal = &basePrice
+		result.HargaSampai = priceRule.Selesai
+	}
+	if item, ok := flashItems[product.ID]; ok {
+		flashRes := flashsale.ToItemRes(item)
+		result.FlashSale = &flashRes
 	}
 
 	return result, nil
@@ -189,10 +259,10 @@ func (s *service) DeleteProduct(ctx context.Context, productID string) error {
 	}
 
 	userID := token.Claims.ID
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_PRODUCT_MANAGE_ANY)
 
 	var shop shop.Toko
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).First(&shop, "id_user = ?", userID).Error; err != nil {
 			return apierror.FromErr(err)
 		}
@@ -208,18 +278,16 @@ func (s *service) DeleteProduct(ctx context.Context, productID string) error {
 		return apierror.FromErr(err)
 	}
 
-	if !isAdmin && product.IdToko != shop.ID {
+	if !manageAny && product.IdToko != shop.ID {
 		return apierror.NewWarn(http.StatusForbidden, "This product is not yours")
 	}
 
-	for _, photo := range product.Photos {
-		_ = os.Remove("." + photo.Url)
-	}
-
 	if err := s.db.WithContext(ctx).Delete(&product).Error; err != nil {
 		return fmt.Errorf("error deleting product details: %v", err)
 	}
 
+	s.removePhotos(ctx, product.Photos)
+
 	return nil
 }
 
@@ -248,18 +316,25 @@ func (s *service) UpdateProduct(ctx context.Context, input UpdateProductReq, pro
 	}
 
 	var product Product
-	if err := tx.First(&product, "id_toko = ? AND id = ?", shop.ID, productId).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id_toko = ? AND id = ?", shop.ID, productId).Error; err != nil {
 		tx.Rollback()
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, product not found")
 	}
 
+	if err := ensureBaselineVersion(tx, product); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	if input.NamaProduk != nil {
 		product.NamaProduk = *input.NamaProduk
 	}
 	if input.Slug != nil {
 		product.Slug = *input.Slug
 	}
+	categoryChanged := false
 	if input.IdCategory != nil {
+		categoryChanged = product.IdCategory != uint(*input.IdCategory)
 		product.IdCategory = uint(*input.IdCategory)
 	}
 	if input.HargaReseller != nil {
@@ -268,85 +343,90 @@ func (s *service) UpdateProduct(ctx context.Context, input UpdateProductReq, pro
 	if input.HargaKonsumen != nil {
 		product.HargaKonsumen = *input.HargaKonsumen
 	}
-	if input.Stok != nil {
-		product.Stok = *input.Stok
-	}
 	if input.Deskripsi != nil {
 		product.Deskripsi = *input.Deskripsi
 	}
 	product.UpdatedAtDate = time.Now()
 
+	actorID := uint(UserID)
+	// a new stok from the edit form is booked as a correction of the difference
+	if input.Stok != nil && *input.Stok != product.Stok {
+		movement, err := MoveStock(tx, StockChange{
+			ProductID: product.ID,
+			Jenis:     STOCK_CORRECTION,
+			Kuantitas: *input.Stok - product.Stok,
+			Alasan:    "edit produk",
+			ActorID:   &actorID,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		product.Stok = movement.StokSetelah
+	}
+
 	if err := tx.Save(&product).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	var photos []Photo
+	if input.Attributes != nil || categoryChanged {
+		if err := s.updateAttributes(ctx, tx, product, input.Attributes, categoryChanged); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := recordVersion(tx, product, &actorID, ""); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var photos, oldPhotos []Photo
 	if input.Photos != nil {
-		var oldPhotos []Photo
 		if err := tx.Where("id_produk = ?", product.ID).Find(&oldPhotos).Error; err != nil {
 			tx.Rollback()
 			return nil, err
 		}
-		for _, p := range oldPhotos {
-			fullPath := filepath.Clean("." + p.Url)
-			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
-				tx.Rollback()
-				return nil, fmt.Errorf("failed to delete old photo: %v", err)
-			}
-		}
 
 		if err := tx.Where("id_produk = ?", product.ID).Delete(&Photo{}).Error; err != nil {
 			tx.Rollback()
 			return nil, err
 		}
 
-		for _, file := range *input.Photos {
-			ext := filepath.Ext(file.Filename)
-			filename, err := fileutils.GenerateMediaName(strconv.Itoa(int(product.ID)))
-			if err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("error generating image name: %v", err)
-			}
-			filename = fmt.Sprintf("%s%s", filename, ext)
-			path := filepath.Join("uploads", "products", filename)
-
-			if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("failed to create directory: %v", err)
-			}
-
-			if err := fileutils.SaveMedia(ctx, file, path); err != nil {
-				tx.Rollback()
-				return nil, err
-			}
-
-			photos = append(photos, Photo{
-				IdProduk: product.ID,
-				Url:      "/uploads/products/" + filename,
-			})
+		photos, err = s.savePhotos(ctx, product.ID, *input.Photos)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
 		}
 
 		if len(photos) > 0 {
 			if err := tx.Create(&photos).Error; err != nil {
 				tx.Rollback()
+				s.removePhotos(ctx, photos)
 				return nil, err
 			}
 		}
 	}
 
 	if err := tx.Commit().Error; err != nil {
+		s.removePhotos(ctx, photos)
 		return nil, err
 	}
 
+	// old files go only after the commit, a failed update keeps the old photos intact
+	s.removePhotos(ctx, oldPhotos)
+
 	return &ProductRes{
 		ID:         int(product.ID),
 		NamaProduk: &product.NamaProduk,
+		Photos:     ToPhotoRes(s.store, photos),
 	}, nil
 }
 
 func (s *service) GetProducts(ctx context.Context, filter GetProductReq) ([]ProductRes, error) {
 	var products []Product
+	now := time.Now()
 
 	db := s.db.WithContext(ctx).Model(&Product{})
 
@@ -354,20 +434,40 @@ func (s *service) GetProducts(ctx context.Context, filter GetProductReq) ([]Prod
 		db = db.Where("nama_produk LIKE ?", "%"+filter.Keyword+"%")
 	}
 
+	tree, err := category.LoadTree(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	if filter.CategoryID != nil {
-		db = db.Where("id_category = ?", *filter.CategoryID)
+		// a parent category also matches products filed under any of its subcategories
+		db = db.Where("id_category IN ?", tree.Descendants(*filter.CategoryID))
 	}
 
 	if filter.TokoID != nil {
 		db = db.Where("id_toko = ?", *filter.TokoID)
 	}
 
+	for _, attr := range filter.Attributes {
+		exists := "EXISTS (SELECT 1 FROM produk_attribute pa WHERE pa.id_produk = produk.id AND pa.kode = ?"
+		if attr.Value != nil {
+			db = db.Where(exists+" AND pa.nilai = ?)", attr.Kode, *attr.Value)
+		}
+		if attr.Min != nil {
+			db = db.Where(exists+" AND CAST(pa.nilai AS DECIMAL(20,6)) >= ?)", attr.Kode, *attr.Min)
+		}
+		if attr.Max != nil {
+			db = db.Where(exists+" AND CAST(pa.nilai AS DECIMAL(20,6)) <= ?)", attr.Kode, *attr.Max)
+		}
+	}
+
+	// price filters and sorting use the price a buyer would pay right now
 	if filter.MinHarga != nil {
-		db = db.Where("CAST(harga_konsumen AS UNSIGNED) >= ?", *filter.MinHarga)
+		db = db.Where("? >= ?", effectivePrice(now), *filter.MinHarga)
 	}
 
 	if filter.MaxHarga != nil {
-		db = db.Where("CAST(harga_konsumen AS UNSIGNED) <= ?", *filter.MaxHarga)
+		db = db.Where("? <= ?", effectivePrice(now), *filter.MaxHarga)
 	}
 
 	if filter.StartCreatedAt != nil {
@@ -383,21 +483,51 @@ func (s *service) GetProducts(ctx context.Context, filter GetProductReq) ([]Prod
 		db = db.Where("updated_at_date <= ?", *filter.EndUpdatedAt)
 	}
 
-	orderStr := fmt.Sprintf("%s %s", filter.OrderBy, strings.ToUpper(filter.SortOrder))
-	db = db.Order(orderStr)
+	if filter.OrderBy == "harga_konsumen" {
+		db = db.Order(clause.OrderBy{Expression: gorm.Expr("? "+strings.ToUpper(filter.SortOrder), effectivePrice(now))})
+	} else {
+		orderStr := fmt.Sprintf("%s %s", filter.OrderBy, strings.ToUpper(filter.SortOrder))
+		db = db.Order(orderStr)
+	}
 
 	offset := (filter.Page - 1) * filter.Limit
 	db = db.Limit(int(filter.Limit)).Offset(int(offset))
 
-	db = db.Preload("Photos")
+	db = db.Preload("Photos", OrderPhotos).Preload("Attributes")
 
 	if err := db.Find(&products).Error; err != nil {
 		return nil, err
 	}
 
+	productIDs := make([]uint, 0, len(products))
+	for _, p := range products {
+		productIDs = append(productIDs, p.ID)
+	}
+
+	ratings, err := shop.GetRatings(s.db.WithContext(ctx), shop.RATING_BY_PRODUCT, productIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	basePrices := make(map[uint]string, len(products))
+	for _, p := range products {
+		basePrices[p.ID] = p.HargaKonsumen
+	}
+
+	priceRules, err := ApplyEffectivePrices(s.db.WithContext(ctx), products, now)
+	if err != nil {
+		return nil, err
+	}
+
+	flashItems, err := flashsale.ActiveItems(s.db.WithContext(ctx), productIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
 	var result []ProductRes
 	for _, p := range products {
 		p := p
+		rating := ratings[p.ID]
 		res := ProductRes{
 			ID:            int(p.ID),
 			NamaProduk:    &p.NamaProduk,
@@ -407,9 +537,1033 @@ func (s *service) GetProducts(ctx context.Context, filter GetProductReq) ([]Prod
 			HargaKonsumen: &p.HargaKonsumen,
 			Stok:          &p.Stok,
 			Deskripsi:     &p.Deskripsi,
+			Breadcrumbs:   tree.Breadcrumbs(p.IdCategory),
+			Photos:        ToPhotoRes(s.store, p.Photos),
+			Attributes:    ToAttributeMap(p.Attributes),
+			Rating:        &rating,
+		}
+		if rule, ok := priceRules[p.ID]; ok {
+			base := basePrices[p.ID]
+			res.HargaNormal = &base
+			res.HargaSampai = rule.Selesai
+		}
+		if item, ok := flashItems[p.ID]; ok {
+			flashRes := flashsale.ToItemRes(item)
+			res.FlashSale = &flashRes
 		}
 		result = append(result, res)
 	}
 
 	return result, nil
 }
+
+func (s *service) AddPhoto(ctx context.Context, productID string, file *multipart.FileHeader) ([]PhotoRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := s.savePhotos(ctx, product.ID, []*multipart.FileHeader{file})
+	if err != nil {
+		return nil, err
+	}
+	photo := &photos[0]
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []Photo
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id_produk = ?", product.ID).
+			Find(&existing).Error; err != nil {
+			return err
+		}
+
+		photo.Urutan = 0
+		for _, p := range existing {
+			photo.Urutan = max(photo.Urutan, p.Urutan+1)
+		}
+		photo.IsPrimary = len(existing) == 0
+
+		return tx.Create(photo).Error
+	})
+	if err != nil {
+		s.removePhotos(ctx, photos)
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.listPhotos(ctx, product.ID)
+}
+
+func (s *service) DeletePhoto(ctx context.Context, productID string, photoID string) ([]PhotoRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var photo Photo
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&photo, "id = ? AND id_produk = ?", photoID, product.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, ErrPhotoNotFound)
+			}
+			return err
+		}
+
+		if err := tx.Delete(&photo).Error; err != nil {
+			return err
+		}
+
+		if !photo.IsPrimary {
+			return nil
+		}
+
+		// promote the next photo so the product keeps a primary one
+		var next Photo
+		if err := tx.Scopes(OrderPhotos).Where("id_produk = ?", product.ID).First(&next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	s.removePhotos(ctx, []Photo{photo})
+
+	return s.listPhotos(ctx, product.ID)
+}
+
+func (s *service) ReorderPhotos(ctx context.Context, productID string, input ReorderPhotoReq) ([]PhotoRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var photos []Photo
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id_produk = ?", product.ID).
+			Find(&photos).Error; err != nil {
+			return err
+		}
+
+		// the new order must mention every photo of the product exactly once
+		owned := make(map[uint]bool, len(photos))
+		for _, p := range photos {
+			owned[p.ID] = true
+		}
+		if len(input.PhotoIDs) != len(photos) {
+			return apierror.NewWarn(http.StatusBadRequest, ErrPhotoOrderIncomplete)
+		}
+		for _, id := range input.PhotoIDs {
+			if !owned[id] {
+				return apierror.NewWarn(http.StatusBadRequest, ErrPhotoOrderIncomplete)
+			}
+			delete(owned, id)
+		}
+
+		for i, id := range input.PhotoIDs {
+			if err := tx.Model(&Photo{}).Where("id = ?", id).Update("urutan", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.listPhotos(ctx, product.ID)
+}
+
+func (s *service) SetPrimaryPhoto(ctx context.Context, productID string, photoID string) ([]PhotoRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var photo Photo
+		if err := tx.First(&photo, "id = ? AND id_produk = ?", photoID, product.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, ErrPhotoNotFound)
+			}
+			return err
+		}
+
+		if err := tx.Model(&Photo{}).Where("id_produk = ?", product.ID).Update("is_primary", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&photo).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.listPhotos(ctx, product.ID)
+}
+
+// findOwnedProduct loads a product the caller may manage: their own, or any for admins.
+func (s *service) findOwnedProduct(ctx context.Context, productID string) (*Product, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var product Product
+	if err := s.db.WithContext(ctx).First(&product, "id = ?", productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusNotFound, "Product not found")
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	if token.Can(user.PERM_PRODUCT_MANAGE_ANY) {
+		return &product, nil
+	}
+
+	var shop shop.Toko
+	if err := s.db.WithContext(ctx).First(&shop, "id_user = ?", token.Claims.ID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
+	}
+	if product.IdToko != shop.ID {
+		return nil, apierror.NewWarn(http.StatusForbidden, "This product is not yours")
+	}
+
+	return &product, nil
+}
+
+func (s *service) listPhotos(ctx context.Context, productID uint) ([]PhotoRes, error) {
+	var photos []Photo
+	if err := s.db.WithContext(ctx).Scopes(OrderPhotos).Where("id_produk = ?", productID).Find(&photos).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	return ToPhotoRes(s.store, photos), nil
+}
+
+// savePhotos runs every upload through the image pipeline and stores the resized
+// variants. Objects already stored are removed again if a later one fails.
+func (s *service) savePhotos(ctx context.Context, productID uint, files []*multipart.FileHeader) ([]Photo, error) {
+	var photos []Photo
+	for i, file := range files {
+		variants, err := imageutils.Process(file, imageutils.ProductVariants)
+		if err != nil {
+			s.removePhotos(ctx, photos)
+			return nil, err
+		}
+
+		name, err := fileutils.GenerateMediaName(strconv.Itoa(int(productID)))
+		if err != nil {
+			s.removePhotos(ctx, photos)
+			return nil, fmt.Errorf("error generating image name: %v", err)
+		}
+
+		photo := Photo{
+			IdProduk:      productID,
+			Urutan:        i,
+			IsPrimary:     i == 0,
+			CreatedAtDate: time.Now(),
+			UpdatedAtDate: time.Now(),
+		}
+		for _, v := range variants {
+			key := fmt.Sprintf("products/%s_%s.jpg", name, v.Variant.Name)
+			if err := s.store.Put(ctx, key, v.Data, "image/jpeg"); err != nil {
+				s.removePhotos(ctx, append(photos, photo))
+				return nil, fmt.Errorf("failed to save photo: %v", err)
+			}
+
+			switch v.Variant.Name {
+			case "thumb":
+				photo.UrlThumb = key
+			case "medium":
+				photo.UrlMedium = key
+			case "large":
+				photo.UrlLarge = key
+			}
+		}
+		photo.Url = photo.UrlLarge
+
+		photos = append(photos, photo)
+	}
+
+	return photos, nil
+}
+
+func photoKeys(p Photo) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, key := range []string{p.Url, p.UrlThumb, p.UrlMedium, p.UrlLarge} {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *service) removePhotos(ctx context.Context, photos []Photo) {
+	for _, p := range photos {
+		for _, key := range photoKeys(p) {
+			_ = s.store.Delete(ctx, key)
+		}
+	}
+}
+
+func parseAttributes(raw string) (map[string]any, error) {
+	values := map[string]any{}
+	if strings.TrimSpace(raw) == "" {
+		return values, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	if err := decoder.Decode(&values); err != nil || values == nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrInvalidAttributes)
+	}
+	return values, nil
+}
+
+// resolveAttributes validates values against the schema of categoryID and its ancestors.
+func (s *service) resolveAttributes(ctx context.Context, categoryID uint, values map[string]any) ([]ProductAttribute, error) {
+	schema, err := category.EffectiveAttributes(ctx, s.db, categoryID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	normalized, err := category.ValidateAttributes(schema, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildAttributes(schema, normalized), nil
+}
+
+func buildAttributes(schema []category.Attribute, normalized map[string]string) []ProductAttribute {
+	var attributes []ProductAttribute
+	for _, attr := range schema {
+		if value, ok := normalized[attr.Kode]; ok {
+			attributes = append(attributes, ProductAttribute{
+				Kode:          attr.Kode,
+				Nilai:         value,
+				CreatedAtDate: time.Now(),
+				UpdatedAtDate: time.Now(),
+			})
+		}
+	}
+	return attributes
+}
+
+// updateAttributes replaces the stored values. Without new values the current ones are
+// re-checked, after a category change only those the new schema knows are carried over.
+func (s *service) updateAttributes(ctx context.Context, tx *gorm.DB, product Product, raw *string, categoryChanged bool) error {
+	var values map[string]any
+	if raw != nil {
+		parsed, err := parseAttributes(*raw)
+		if err != nil {
+			return err
+		}
+		values = parsed
+	} else {
+		var current []ProductAttribute
+		if err := tx.Where("id_produk = ?", product.ID).Find(&current).Error; err != nil {
+			return err
+		}
+
+		schema, err := category.EffectiveAttributes(ctx, s.db, product.IdCategory)
+		if err != nil {
+			return err
+		}
+		known := map[string]bool{}
+		for _, attr := range schema {
+			known[attr.Kode] = true
+		}
+
+		values = map[string]any{}
+		for _, attr := range current {
+			if !categoryChanged || known[attr.Kode] {
+				values[attr.Kode] = attr.Nilai
+			}
+		}
+	}
+
+	attributes, err := s.resolveAttributes(ctx, product.IdCategory, values)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Where("id_produk = ?", product.ID).Delete(&ProductAttribute{}).Error; err != nil {
+		return err
+	}
+	return saveAttributes(tx, product.ID, attributes)
+}
+
+func saveAttributes(tx *gorm.DB, productID uint, attributes []ProductAttribute) error {
+	if len(attributes) == 0 {
+		return nil
+	}
+	for i := range attributes {
+		attributes[i].IdProduk = productID
+	}
+	return tx.Create(&attributes).Error
+}
+
+// GetProductHistory lists every recorded version, newest first, with the fields
+// that changed compared to the version before it.
+func (s *service) GetProductHistory(ctx context.Context, productID string) ([]VersionRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []LogProduk
+	if err := s.db.WithContext(ctx).
+		Where("id_produk = ? AND jenis = ?", product.ID, LOG_VERSION).
+		Order("versi ASC").
+		Find(&versions).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var userIDs []uint
+	for _, v := range versions {
+		if v.IdUser != nil {
+			userIDs = append(userIDs, *v.IdUser)
+		}
+	}
+	names := map[uint]string{}
+	if len(userIDs) > 0 {
+		var users []user.User
+		if err := s.db.WithContext(ctx).Select("id", "nama").Find(&users, "id IN ?", userIDs).Error; err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		for _, u := range users {
+			names[u.ID] = u.Nama
+		}
+	}
+
+	res := make([]VersionRes, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		version := VersionRes{
+			Versi:     *v.Versi,
+			Catatan:   v.Catatan,
+			Changes:   []FieldChangeRes{},
+			CreatedAt: v.CreatedAtDate,
+		}
+		if v.IdUser != nil {
+			version.ChangedBy = &VersionUserRes{ID: int(*v.IdUser), Nama: names[*v.IdUser]}
+		}
+		if i > 0 {
+			version.Changes = diffVersions(versions[i-1], v)
+		}
+		res = append(res, version)
+	}
+
+	return res, nil
+}
+
+// RevertProduct restores the content and prices of an earlier version as a new version.
+// Stock is left alone, it moves with sales and restocks rather than with edits.
+func (s *service) RevertProduct(ctx context.Context, productID string, versi string) (res *ProductRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	var product Product
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, "id = ?", productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, "Product not found")
+			}
+			return err
+		}
+
+		var version LogProduk
+		if err := tx.First(&version, "id_produk = ? AND jenis = ? AND versi = ?", product.ID, LOG_VERSION, versi).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, ErrVersionNotFound)
+			}
+			return err
+		}
+
+		if err := s.checkRevertCategory(ctx, tx, product, version); err != nil {
+			return err
+		}
+
+		if err := ensureBaselineVersion(tx, product); err != nil {
+			return err
+		}
+
+		product.NamaProduk = version.NamaProduk
+		product.Slug = version.Slug
+		product.HargaReseller = version.HargaReseller
+		product.HargaKonsumen = version.HargaKonsumen
+		product.Deskripsi = version.Deskripsi
+		product.IdCategory = *version.IdCategory
+		product.UpdatedAtDate = time.Now()
+
+		if err := tx.Omit(clause.Associations).Save(&product).Error; err != nil {
+			return err
+		}
+
+		return recordVersion(tx, product, &actorID, fmt.Sprintf("revert to version %d", *version.Versi))
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.GetProductByID(ctx, strconv.Itoa(int(product.ID)))
+}
+
+// checkRevertCategory refuses a revert to a category that was deleted since, or whose
+// schema the product's current attributes don't satisfy.
+func (s *service) checkRevertCategory(ctx context.Context, tx *gorm.DB, product Product, version LogProduk) error {
+	if version.IdCategory == nil {
+		return apierror.NewWarn(http.StatusConflict, ErrVersionCategory)
+	}
+
+	var count int64
+	if err := tx.Model(&category.Category{}).Where("id = ?", *version.IdCategory).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return apierror.NewWarn(http.StatusConflict, ErrVersionCategory)
+	}
+
+	var current []ProductAttribute
+	if err := tx.Where("id_produk = ?", product.ID).Find(&current).Error; err != nil {
+		return err
+	}
+	values := make(map[string]any, len(current))
+	for _, attr := range current {
+		values[attr.Kode] = attr.Nilai
+	}
+
+	schema, err := category.EffectiveAttributes(ctx, tx, *version.IdCategory)
+	if err != nil {
+		return err
+	}
+	if _, err := category.ValidateAttributes(schema, values); err != nil {
+		return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrVersionAttributes, strings.Join(apierror.GetApiErrors(err).Messages, ", ")))
+	}
+	return nil
+}
+
+// recordVersion appends the current state of product as its next version.
+func recordVersion(tx *gorm.DB, product Product, actorID *uint, catatan string) error {
+	var last int
+	if err := tx.Model(&LogProduk{}).
+		Where("id_produk = ? AND jenis = ?", product.ID, LOG_VERSION).
+		Select("COALESCE(MAX(versi), 0)").
+		Scan(&last).Error; err != nil {
+		return err
+	}
+
+	versi := last + 1
+	stok := product.Stok
+	return tx.Create(&LogProduk{
+		IdProduk:      product.ID,
+		NamaProduk:    product.NamaProduk,
+		Slug:          product.Slug,
+		HargaReseller: product.HargaReseller,
+		HargaKonsumen: product.HargaKonsumen,
+		Deskripsi:     product.Deskripsi,
+		IdToko:        product.IdToko,
+		IdCategory:    &product.IdCategory,
+		Jenis:         LOG_VERSION,
+		Versi:         &versi,
+		Stok:          &stok,
+		IdUser:        actorID,
+		Catatan:       catatan,
+		CreatedAtDate: time.Now(),
+		UpdatedAtDate: time.Now(),
+	}).Error
+}
+
+// ensureBaselineVersion records the pre-edit state of products created before
+// versioning existed, so their first edit still has something to diff against.
+func ensureBaselineVersion(tx *gorm.DB, product Product) error {
+	var count int64
+	if err := tx.Model(&LogProduk{}).
+		Where("id_produk = ? AND jenis = ?", product.ID, LOG_VERSION).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordVersion(tx, product, nil, "baseline")
+}
+
+func diffVersions(prev, cur LogProduk) []FieldChangeRes {
+	stok := func(v *int) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	}
+	categoryID := func(v *uint) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(int(*v))
+	}
+
+	fields := []FieldChangeRes{
+		{Field: "nama_produk", From: prev.NamaProduk, To: cur.NamaProduk},
+		{Field: "slug", From: prev.Slug, To: cur.Slug},
+		{Field: "category_id", From: categoryID(prev.IdCategory), To: categoryID(cur.IdCategory)},
+		{Field: "harga_reseller", From: prev.HargaReseller, To: cur.HargaReseller},
+		{Field: "harga_konsumen", From: prev.HargaKonsumen, To: cur.HargaKonsumen},
+		{Field: "stok", From: stok(prev.Stok), To: stok(cur.Stok)},
+		{Field: "deskripsi", From: prev.Deskripsi, To: cur.Deskripsi},
+	}
+
+	changes := []FieldChangeRes{}
+	for _, f := range fields {
+		if f.From != f.To {
+			changes = append(changes, f)
+		}
+	}
+	return changes
+}
+
+// GetPriceRules is the price schedule of a product, past rules included.
+func (s *service) GetPriceRules(ctx context.Context, productID string) ([]PriceRuleRes, error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []PriceRule
+	if err := s.db.WithContext(ctx).
+		Where("id_produk = ?", product.ID).
+		Order("mulai DESC, id DESC").
+		Find(&rules).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	now := time.Now()
+	res := make([]PriceRuleRes, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, toPriceRuleRes(rule, now))
+	}
+	return res, nil
+}
+
+func (s *service) AddPriceRule(ctx context.Context, productID string, input PriceRuleReq) (res *PriceRuleRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if input.Selesai != nil && (!input.Selesai.After(input.Mulai) || !input.Selesai.After(now)) {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrPriceRuleRange)
+	}
+
+	rule := PriceRule{
+		IdProduk:      product.ID,
+		HargaKonsumen: input.HargaKonsumen,
+		HargaReseller: input.HargaReseller,
+		Mulai:         input.Mulai,
+		Selesai:       input.Selesai,
+		IdUser:        &actorID,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// lock the product so two overlapping rules can't be added side by side
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&Product{}, "id = ?", product.ID).Error; err != nil {
+			return err
+		}
+
+		var existing []PriceRule
+		if err := tx.Where("id_produk = ? AND (selesai IS NULL OR selesai > ?)", product.ID, now).Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, other := range existing {
+			if rulesOverlap(rule, other) {
+				return apierror.NewWarn(http.StatusConflict, ErrPriceRuleOverlap)
+			}
+		}
+
+		return tx.Create(&rule).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := toPriceRuleRes(rule, now)
+	return &result, nil
+}
+
+// DeletePriceRule removes a rule that has not ended yet, expired rules are kept as price history.
+func (s *service) DeletePriceRule(ctx context.Context, productID string, ruleID string) error {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND id_produk = ? AND (selesai IS NULL OR selesai > ?)", ruleID, product.ID, time.Now()).
+		Delete(&PriceRule{})
+	if result.Error != nil {
+		return apierror.FromErr(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apierror.NewWarn(http.StatusNotFound, ErrPriceRuleNotFound)
+	}
+
+	return nil
+}
+
+func toPriceRuleRes(rule PriceRule, now time.Time) PriceRuleRes {
+	return PriceRuleRes{
+		ID:            int(rule.ID),
+		HargaKonsumen: rule.HargaKonsumen,
+		HargaReseller: rule.HargaReseller,
+		Mulai:         rule.Mulai,
+		Selesai:       rule.Selesai,
+		Status:        priceRuleStatus(rule, now),
+	}
+}
+
+// ImportProducts checks every row against the seller's shop, categories and attribute
+// schemas. Rows are only written in commit mode and only when no row failed, so a file
+// is either imported entirely or not at all.
+func (s *service) ImportProducts(ctx context.Context, rows []ImportRow, commit bool) (res *ImportRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	var toko shop.Toko
+	if err := s.db.WithContext(ctx).First(&toko, "id_user = ?", actorID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
+	}
+
+	var productIDs, categoryIDs []uint
+	for _, row := range rows {
+		if row.ID != nil {
+			productIDs = append(productIDs, *row.ID)
+		}
+		categoryIDs = append(categoryIDs, row.Product.IdCategory)
+	}
+
+	owned := map[uint]bool{}
+	if len(productIDs) > 0 {
+		var ids []uint
+		if err := s.db.WithContext(ctx).Model(&Product{}).
+			Where("id_toko = ? AND id IN ?", toko.ID, productIDs).
+			Pluck("id", &ids).Error; err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		for _, id := range ids {
+			owned[id] = true
+		}
+	}
+
+	categories := map[uint]bool{}
+	var ids []uint
+	if err := s.db.WithContext(ctx).Model(&category.Category{}).
+		Where("id IN ?", categoryIDs).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	for _, id := range ids {
+		categories[id] = true
+	}
+
+	type plan struct {
+		row        ImportRow
+		attributes []ProductAttribute
+		result     *ImportRowRes
+	}
+
+	mode := IMPORT_MODE_DRY_RUN
+	if commit {
+		mode = IMPORT_MODE_COMMIT
+	}
+	res = &ImportRes{Mode: mode, Total: len(rows), Rows: make([]ImportRowRes, len(rows))}
+
+	schemas := map[uint][]category.Attribute{}
+	seen := map[uint]bool{}
+	var plans []plan
+
+	for i, row := range rows {
+		result := &res.Rows[i]
+		result.Row = row.Row
+		result.Action = IMPORT_ACTION_CREATE
+		result.Errors = row.Errors
+
+		if row.ID != nil {
+			result.Action = IMPORT_ACTION_UPDATE
+			id := int(*row.ID)
+			result.ID = &id
+
+			switch {
+			case seen[*row.ID]:
+				result.Errors = append(result.Errors, fmt.Sprintf(ErrImportDuplicateID, *row.ID))
+			case !owned[*row.ID]:
+				result.Errors = append(result.Errors, fmt.Sprintf(ErrImportNotOwned, *row.ID))
+			}
+			seen[*row.ID] = true
+		}
+
+		if row.Product.IdCategory != 0 && !categories[row.Product.IdCategory] {
+			result.Errors = append(result.Errors, fmt.Sprintf(ErrImportCategory, row.Product.IdCategory))
+		}
+
+		if len(result.Errors) > 0 {
+			res.Failed++
+			continue
+		}
+
+		// the schema only depends on the category, rows share it instead of reloading per row
+		schema, ok := schemas[row.Product.IdCategory]
+		if !ok {
+			schema, err = category.EffectiveAttributes(ctx, s.db, row.Product.IdCategory)
+			if err != nil {
+				return nil, apierror.FromErr(err)
+			}
+			schemas[row.Product.IdCategory] = schema
+		}
+
+		var normalized map[string]string
+		values, err := parseAttributes(row.Product.Attributes)
+		if err == nil {
+			normalized, err = category.ValidateAttributes(schema, values)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, apierror.GetApiErrors(err).Messages...)
+			res.Failed++
+			continue
+		}
+		attributes := buildAttributes(schema, normalized)
+
+		if row.ID != nil {
+			res.Updated++
+		} else {
+			res.Created++
+		}
+		plans = append(plans, plan{row: row, attributes: attributes, result: result})
+	}
+
+	if !commit || res.Failed > 0 {
+		return res, nil
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, p := range plans {
+			input := p.row.Product
+
+			var product Product
+			if p.row.ID != nil {
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					First(&product, "id = ? AND id_toko = ?", *p.row.ID, toko.ID).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrImportNotOwned, *p.row.ID))
+					}
+					return err
+				}
+				if err := ensureBaselineVersion(tx, product); err != nil {
+					return err
+				}
+			} else {
+				product = Product{IdToko: toko.ID, CreatedAtDate: time.Now()}
+			}
+
+			product.NamaProduk = input.NamaProduk
+			product.Slug = ""
+			if input.Slug != nil {
+				product.Slug = *input.Slug
+			}
+			product.IdCategory = input.IdCategory
+			product.HargaReseller = input.HargaReseller
+			product.HargaKonsumen = input.HargaKonsumen
+			product.Deskripsi = input.Deskripsi
+			product.UpdatedAtDate = time.Now()
+
+			if err := tx.Save(&product).Error; err != nil {
+				return err
+			}
+
+			if delta := input.Stok - product.Stok; delta != 0 {
+				jenis, alasan := STOCK_CORRECTION, "import"
+				if p.row.ID == nil {
+					jenis, alasan = STOCK_RESTOCK, "stok awal"
+				}
+				movement, err := MoveStock(tx, StockChange{
+					ProductID: product.ID,
+					Jenis:     jenis,
+					Kuantitas: delta,
+					Alasan:    alasan,
+					Referensi: "import",
+					ActorID:   &actorID,
+				})
+				if err != nil {
+					return err
+				}
+				product.Stok = movement.StokSetelah
+			}
+
+			if err := tx.Where("id_produk = ?", product.ID).Delete(&ProductAttribute{}).Error; err != nil {
+				return err
+			}
+			if err := saveAttributes(tx, product.ID, p.attributes); err != nil {
+				return err
+			}
+
+			if err := recordVersion(tx, product, &actorID, "import"); err != nil {
+				return err
+			}
+
+			id := int(product.ID)
+			p.result.ID = &id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res.Committed = true
+	return res, nil
+}
+
+// ExportProducts resolves the seller's shop right away and returns a function that walks
+// its catalog in batches. The walk runs while the response is streamed, after the handler
+// has returned, so it doesn't use the request context.
+func (s *service) ExportProducts(ctx context.Context) (func(write func(Product) error) error, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var toko shop.Toko
+	if err := s.db.WithContext(ctx).First(&toko, "id_user = ?", token.Claims.ID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
+	}
+
+	return func(write func(Product) error) error {
+		var products []Product
+		return s.db.Preload("Attributes").
+			Where("id_toko = ?", toko.ID).
+			FindInBatches(&products, EXPORT_BATCH, func(tx *gorm.DB, batch int) error {
+				for _, p := range products {
+					if err := write(p); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+	}, nil
+}
+
+// GetStockLedger pages through the movements of a product and checks that the ledger
+// still adds up to the stored stock.
+func (s *service) GetStockLedger(ctx context.Context, productID string, filter *constants.FilterReq) (res *StockLedgerRes, err error) {
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger, err := LedgerStock(s.db.WithContext(ctx), product.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var movements []StockMovement
+	offset := (filter.Page - 1) * filter.Limit
+	if err := s.db.WithContext(ctx).
+		Where("id_produk = ?", product.ID).
+		Order(fmt.Sprintf("%s %s, id %s", filter.OrderBy, filter.SortOrder, filter.SortOrder)).
+		Limit(int(filter.Limit)).
+		Offset(int(offset)).
+		Find(&movements).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var userIDs []uint
+	for _, m := range movements {
+		if m.IdUser != nil {
+			userIDs = append(userIDs, *m.IdUser)
+		}
+	}
+	names := map[uint]string{}
+	if len(userIDs) > 0 {
+		var users []user.User
+		if err := s.db.WithContext(ctx).Select("id", "nama").Find(&users, "id IN ?", userIDs).Error; err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		for _, u := range users {
+			names[u.ID] = u.Nama
+		}
+	}
+
+	res = &StockLedgerRes{
+		Stok:       product.Stok,
+		StokLedger: ledger,
+		Sesuai:     ledger == product.Stok,
+		Mutasi:     make([]StockMovementRes, 0, len(movements)),
+		Page:       int(filter.Page),
+		Limit:      int(filter.Limit),
+	}
+	for _, m := range movements {
+		res.Mutasi = append(res.Mutasi, toStockMovementRes(m, names))
+	}
+	return res, nil
+}
+
+func (s *service) AdjustStock(ctx context.Context, productID string, input StockAdjustmentReq) (res *StockMovementRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	if input.Jenis == STOCK_RESTOCK && input.Kuantitas <= 0 {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrStockRestock)
+	}
+
+	product, err := s.findOwnedProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var movement *StockMovement
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		movement, err = MoveStock(tx, StockChange{
+			ProductID: product.ID,
+			Jenis:     input.Jenis,
+			Kuantitas: input.Kuantitas,
+			Alasan:    input.Alasan,
+			ActorID:   &actorID,
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierror.NewWarn(http.StatusNotFound, "Product not found")
+		}
+		return err
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var actor user.User
+	s.db.WithContext(ctx).Select("id", "nama").First(&actor, "id = ?", actorID)
+
+	result := toStockMovementRes(*movement, map[uint]string{actorID: actor.Nama})
+	return &result, nil
+}
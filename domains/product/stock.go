@@ -0,0 +1,87 @@
+package product
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockChange describes one stock movement. Kuantitas is signed: sales are negative,
+// cancellations, returns and restocks positive, corrections either way.
+type StockChange struct {
+	ProductID uint
+	Jenis     string
+	Kuantitas int
+	Alasan    string
+	Referensi string
+	ActorID   *uint
+}
+
+// MoveStock is the only way produk.stok changes. It locks the product row, applies
+// the change and appends it to the ledger in the caller's transaction. A missing
+// product is returned as gorm.ErrRecordNotFound so callers can tell it apart.
+func MoveStock(tx *gorm.DB, change StockChange) (*StockMovement, error) {
+	var product Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "stok").
+		First(&product, "id = ?", change.ProductID).Error; err != nil {
+		return nil, err
+	}
+
+	stok := product.Stok + change.Kuantitas
+	if stok < 0 {
+		return nil, apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrStockInsufficient, change.ProductID))
+	}
+
+	if err := tx.Model(&Product{}).
+		Where("id = ?", change.ProductID).
+		UpdateColumn("stok", stok).Error; err != nil {
+		return nil, err
+	}
+
+	movement := StockMovement{
+		IdProduk:      change.ProductID,
+		Jenis:         change.Jenis,
+		Kuantitas:     change.Kuantitas,
+		StokSetelah:   stok,
+		Alasan:        change.Alasan,
+		Referensi:     change.Referensi,
+		IdUser:        change.ActorID,
+		CreatedAtDate: time.Now(),
+	}
+	if err := tx.Create(&movement).Error; err != nil {
+		return nil, err
+	}
+
+	return &movement, nil
+}
+
+// LedgerStock sums the ledger of a product, which should always equal produk.stok.
+func LedgerStock(db *gorm.DB, productID uint) (int, error) {
+	var total int
+	err := db.Model(&StockMovement{}).
+		Where("id_produk = ?", productID).
+		Select("COALESCE(SUM(kuantitas), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func toStockMovementRes(m StockMovement, names map[uint]string) StockMovementRes {
+	res := StockMovementRes{
+		ID:          int(m.ID),
+		Jenis:       m.Jenis,
+		Kuantitas:   m.Kuantitas,
+		StokSetelah: m.StokSetelah,
+		Alasan:      m.Alasan,
+		Referensi:   m.Referensi,
+		CreatedAt:   m.CreatedAtDate,
+	}
+	if m.IdUser != nil {
+		res.User = &VersionUserRes{ID: int(*m.IdUser), Nama: names[*m.IdUser]}
+	}
+	return res
+}
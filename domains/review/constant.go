@@ -0,0 +1,17 @@
+package review
+
+const (
+	STATUS_VISIBLE = "visible"
+	STATUS_HIDDEN  = "hidden"
+)
+
+const MAX_PHOTOS = 5
+
+const (
+	ErrReviewNotFound   = "Failed, review not found"
+	ErrPurchaseNotFound = "Failed, purchase not found"
+	ErrNotDelivered     = "You can only review a purchase that has been delivered"
+	ErrAlreadyReviewed  = "You have already reviewed this purchase"
+	ErrTooManyPhotos    = "A review can have at most 5 photos"
+	ErrNotYourShop      = "Only the seller of this product can reply to the review"
+)
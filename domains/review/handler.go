@@ -0,0 +1,144 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/common"
+	"github.com/devanadindraa/Evermos-Backend/utils/respond"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler interface {
+	AddReview(ctx *fiber.Ctx) error
+	GetProductReviews(ctx *fiber.Ctx) error
+	ReplyReview(ctx *fiber.Ctx) error
+	ModerateReview(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	service  Service
+	validate *validator.Validate
+}
+
+func NewHandler(service Service, validate *validator.Validate) Handler {
+	return &handler{
+		service:  service,
+		validate: validate,
+	}
+}
+
+func (h *handler) AddReview(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var req ReviewReq
+	if err := ctx.BodyParser(&req); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	// photos are optional, a plain form without files is fine too
+	if form, err := ctx.MultipartForm(); err == nil {
+		req.Photos = append([]*multipart.FileHeader{}, form.File["photos"]...)
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	result, err := h.service.AddReview(reqCtx, req)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST review", result)
+	return nil
+}
+
+func (h *handler) GetProductReviews(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	filter, err := common.GetMetaData(ctx, h.validate, "created_at_date", "rating")
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	result, err := h.service.GetProductReviews(reqCtx, productID, filter)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", result)
+	return nil
+}
+
+func (h *handler) ReplyReview(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	reviewID := ctx.Params("id")
+	if reviewID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input ReplyReviewReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	result, err := h.service.ReplyReview(reqCtx, reviewID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", result)
+	return nil
+}
+
+func (h *handler) ModerateReview(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	reviewID := ctx.Params("id")
+	if reviewID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input ModerateReviewReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	result, err := h.service.ModerateReview(reqCtx, reviewID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", result)
+	return nil
+}
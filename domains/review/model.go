@@ -0,0 +1,36 @@
+package review
+
+import "time"
+
+type Review struct {
+	ID              uint          `gorm:"primaryKey"`
+	IdUser          uint          `gorm:"not null"`
+	IdDetailTrx     uint          `gorm:"not null;unique"`
+	IdProduk        *uint         `json:"id_produk"`
+	IdToko          uint          `gorm:"not null"`
+	Rating          int           `json:"rating"`
+	Ulasan          string        `json:"ulasan"`
+	Balasan         string        `json:"balasan"`
+	BalasanAt       *time.Time    `json:"balasan_at"`
+	Status          string        `json:"status"`
+	CatatanModerasi string        `json:"catatan_moderasi"`
+	Photos          []ReviewPhoto `gorm:"foreignKey:IdReview;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"photos"`
+	CreatedAtDate   time.Time     `gorm:"autoCreateTime"`
+	UpdatedAtDate   time.Time     `gorm:"autoUpdateTime"`
+}
+
+type ReviewPhoto struct {
+	ID            uint      `gorm:"primaryKey"`
+	IdReview      uint      `gorm:"not null"`
+	Url           string    `json:"url"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Review) TableName() string {
+	return "review"
+}
+
+func (ReviewPhoto) TableName() string {
+	return "foto_review"
+}
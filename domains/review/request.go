@@ -0,0 +1,19 @@
+package review
+
+import "mime/multipart"
+
+type ReviewReq struct {
+	IdDetailTrx uint                    `form:"detail_trx_id" validate:"required"`
+	Rating      int                     `form:"rating" validate:"required,min=1,max=5"`
+	Ulasan      string                  `form:"ulasan" validate:"max=2000"`
+	Photos      []*multipart.FileHeader `form:"-"`
+}
+
+type ReplyReviewReq struct {
+	Balasan string `json:"balasan" validate:"required,max=2000"`
+}
+
+type ModerateReviewReq struct {
+	Status          string `json:"status" validate:"required,oneof=visible hidden"`
+	CatatanModerasi string `json:"catatan_moderasi" validate:"max=255"`
+}
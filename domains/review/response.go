@@ -0,0 +1,29 @@
+package review
+
+import (
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/domains/shop"
+)
+
+type ReviewRes struct {
+	ID              int        `json:"id"`
+	IdProduk        *uint      `json:"id_produk"`
+	IdToko          int        `json:"id_toko"`
+	NamaUser        string     `json:"nama_user"`
+	Rating          int        `json:"rating"`
+	Ulasan          string     `json:"ulasan"`
+	Photos          []string   `json:"photos"`
+	Balasan         string     `json:"balasan,omitempty"`
+	BalasanAt       *time.Time `json:"balasan_at,omitempty"`
+	Status          string     `json:"status"`
+	CatatanModerasi string     `json:"catatan_moderasi,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type PaginatedReviewRes struct {
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+	Rating shop.RatingRes `json:"rating"`
+	Data   []ReviewRes    `json:"data"`
+}
@@ -0,0 +1,310 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/domains/shop"
+	"github.com/devanadindraa/Evermos-Backend/domains/trx"
+	"github.com/devanadindraa/Evermos-Backend/domains/user"
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	fileutils "github.com/devanadindraa/Evermos-Backend/utils/file"
+	imageutils "github.com/devanadindraa/Evermos-Backend/utils/image"
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
+	"gorm.io/gorm"
+)
+
+type Service interface {
+	AddReview(ctx context.Context, input ReviewReq) (res *ReviewRes, err error)
+	GetProductReviews(ctx context.Context, productID string, filter *constants.FilterReq) (res *PaginatedReviewRes, err error)
+	ReplyReview(ctx context.Context, reviewID string, input ReplyReviewReq) (res *ReviewRes, err error)
+	ModerateReview(ctx context.Context, reviewID string, input ModerateReviewReq) (res *ReviewRes, err error)
+}
+
+type service struct {
+	authConfig config.Auth
+	db         *gorm.DB
+	store      storage.Store
+}
+
+func NewService(config *config.Config, db *gorm.DB, store storage.Store) Service {
+	return &service{
+		authConfig: config.Auth,
+		db:         db,
+		store:      store,
+	}
+}
+
+func (s *service) AddReview(ctx context.Context, input ReviewReq) (res *ReviewRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	userID := uint(token.Claims.ID)
+
+	if len(input.Photos) > MAX_PHOTOS {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrTooManyPhotos)
+	}
+
+	var detail trx.DetailTrx
+	if err := s.db.WithContext(ctx).First(&detail, "id = ?", input.IdDetailTrx).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrPurchaseNotFound)
+	}
+
+	var order trx.Trx
+	if err := s.db.WithContext(ctx).First(&order, "id = ? AND id_user = ?", detail.IdTrx, userID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrPurchaseNotFound)
+	}
+
+	if detail.Status != trx.STATUS_DELIVERED {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrNotDelivered)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&Review{}).Where("id_detail_trx = ?", detail.ID).Count(&count).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	if count > 0 {
+		return nil, apierror.NewWarn(http.StatusConflict, ErrAlreadyReviewed)
+	}
+
+	var logProduk trx.LogProduk
+	if err := s.db.WithContext(ctx).First(&logProduk, "id = ?", detail.IdLogProduk).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrPurchaseNotFound)
+	}
+
+	// the product may have been deleted since, the review still counts for the shop
+	var productID *uint
+	var products int64
+	if err := s.db.WithContext(ctx).Table("produk").Where("id = ?", logProduk.IdProduk).Count(&products).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	if products > 0 {
+		productID = &logProduk.IdProduk
+	}
+
+	photos, err := s.savePhotos(ctx, detail.ID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	review := Review{
+		IdUser:        userID,
+		IdDetailTrx:   detail.ID,
+		IdProduk:      productID,
+		IdToko:        detail.IdToko,
+		Rating:        input.Rating,
+		Ulasan:        strings.TrimSpace(input.Ulasan),
+		Status:        STATUS_VISIBLE,
+		Photos:        photos,
+		CreatedAtDate: time.Now(),
+		UpdatedAtDate: time.Now(),
+	}
+
+	// the unique id_detail_trx also stops two concurrent reviews of the same line
+	if err := s.db.WithContext(ctx).Create(&review).Error; err != nil {
+		s.removePhotos(ctx, photos)
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "Duplicate entry") {
+			return nil, apierror.NewWarn(http.StatusConflict, ErrAlreadyReviewed)
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.toReviewRes(ctx, review)
+}
+
+func (s *service) GetProductReviews(ctx context.Context, productID string, filter *constants.FilterReq) (res *PaginatedReviewRes, err error) {
+	id, err := strconv.Atoi(productID)
+	if err != nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, "id must be a number")
+	}
+
+	var reviews []Review
+	offset := (filter.Page - 1) * filter.Limit
+
+	if err := s.db.WithContext(ctx).
+		Preload("Photos").
+		Where("id_produk = ? AND status = ?", id, STATUS_VISIBLE).
+		Order(fmt.Sprintf("%s %s", filter.OrderBy, filter.SortOrder)).
+		Limit(int(filter.Limit)).
+		Offset(int(offset)).
+		Find(&reviews).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	rating, err := shop.GetRating(s.db.WithContext(ctx), shop.RATING_BY_PRODUCT, uint(id))
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result, err := s.toReviewsRes(ctx, reviews)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaginatedReviewRes{
+		Page:   int(filter.Page),
+		Limit:  int(filter.Limit),
+		Rating: *rating,
+		Data:   result,
+	}, nil
+}
+
+func (s *service) ReplyReview(ctx context.Context, reviewID string, input ReplyReviewReq) (res *ReviewRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	userID := uint(token.Claims.ID)
+
+	review, err := s.findReview(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	var toko shop.Toko
+	if err := s.db.WithContext(ctx).First(&toko, "id = ?", review.IdToko).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
+	}
+	if toko.IdUser != userID {
+		return nil, apierror.NewWarn(http.StatusForbidden, ErrNotYourShop)
+	}
+
+	now := time.Now()
+	review.Balasan = strings.TrimSpace(input.Balasan)
+	review.BalasanAt = &now
+	review.UpdatedAtDate = now
+
+	if err := s.db.WithContext(ctx).Save(review).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.toReviewRes(ctx, *review)
+}
+
+func (s *service) ModerateReview(ctx context.Context, reviewID string, input ModerateReviewReq) (res *ReviewRes, err error) {
+	review, err := s.findReview(ctx, reviewID)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Status = input.Status
+	review.CatatanModerasi = input.CatatanModerasi
+	review.UpdatedAtDate = time.Now()
+
+	if err := s.db.WithContext(ctx).Save(review).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.toReviewRes(ctx, *review)
+}
+
+func (s *service) findReview(ctx context.Context, reviewID string) (*Review, error) {
+	var review Review
+	if err := s.db.WithContext(ctx).Preload("Photos").First(&review, "id = ?", reviewID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusNotFound, ErrReviewNotFound)
+		}
+		return nil, apierror.FromErr(err)
+	}
+	return &review, nil
+}
+
+func (s *service) toReviewRes(ctx context.Context, review Review) (*ReviewRes, error) {
+	res, err := s.toReviewsRes(ctx, []Review{review})
+	if err != nil {
+		return nil, err
+	}
+	return &res[0], nil
+}
+
+func (s *service) toReviewsRes(ctx context.Context, reviews []Review) ([]ReviewRes, error) {
+	userIDs := make([]uint, 0, len(reviews))
+	for _, r := range reviews {
+		userIDs = append(userIDs, r.IdUser)
+	}
+
+	names := map[uint]string{}
+	if len(userIDs) > 0 {
+		var users []user.User
+		if err := s.db.WithContext(ctx).Select("id", "nama").Find(&users, "id IN ?", userIDs).Error; err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		for _, u := range users {
+			names[u.ID] = u.Nama
+		}
+	}
+
+	result := []ReviewRes{}
+	for _, r := range reviews {
+		photos := []string{}
+		for _, p := range r.Photos {
+			photos = append(photos, s.store.URL(p.Url))
+		}
+
+		result = append(result, ReviewRes{
+			ID:              int(r.ID),
+			IdProduk:        r.IdProduk,
+			IdToko:          int(r.IdToko),
+			NamaUser:        names[r.IdUser],
+			Rating:          r.Rating,
+			Ulasan:          r.Ulasan,
+			Photos:          photos,
+			Balasan:         r.Balasan,
+			BalasanAt:       r.BalasanAt,
+			Status:          r.Status,
+			CatatanModerasi: r.CatatanModerasi,
+			CreatedAt:       r.CreatedAtDate,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *service) savePhotos(ctx context.Context, detailID uint, input ReviewReq) ([]ReviewPhoto, error) {
+	var photos []ReviewPhoto
+	for _, file := range input.Photos {
+		variants, err := imageutils.Process(file, imageutils.ReviewVariants)
+		if err != nil {
+			s.removePhotos(ctx, photos)
+			return nil, err
+		}
+
+		name, err := fileutils.GenerateMediaName(strconv.Itoa(int(detailID)))
+		if err != nil {
+			s.removePhotos(ctx, photos)
+			return nil, fmt.Errorf("error generating image name: %v", err)
+		}
+
+		key := fmt.Sprintf("reviews/%s.jpg", name)
+		if err := s.store.Put(ctx, key, variants[0].Data, "image/jpeg"); err != nil {
+			s.removePhotos(ctx, photos)
+			return nil, fmt.Errorf("failed to save photo: %v", err)
+		}
+
+		photos = append(photos, ReviewPhoto{
+			Url:           key,
+			CreatedAtDate: time.Now(),
+			UpdatedAtDate: time.Now(),
+		})
+	}
+
+	return photos, nil
+}
+
+func (s *service) removePhotos(ctx context.Context, photos []ReviewPhoto) {
+	for _, p := range photos {
+		if err := s.store.Delete(ctx, p.Url); err != nil {
+			logger.Warn(ctx, "failed to remove review photo %s: %v", p.Url, err)
+		}
+	}
+}
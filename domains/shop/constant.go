@@ -0,0 +1,5 @@
+package shop
+
+// PERM_SHOP_MANAGE_ANY lets its holder edit every shop and see the full shop list. It is
+// defined here because user imports shop, user.PERM_SHOP_MANAGE_ANY refers to it.
+const PERM_SHOP_MANAGE_ANY = "shop.manage_any"
@@ -0,0 +1,56 @@
+package shop
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// Columns ratings can be grouped by
+const (
+	RATING_BY_PRODUCT = "id_produk"
+	RATING_BY_SHOP    = "id_toko"
+)
+
+// GetRatings aggregates visible reviews per product or shop straight from the
+// review table, the review domain itself depends on product and shop.
+func GetRatings(db *gorm.DB, column string, ids ...uint) (map[uint]RatingRes, error) {
+	res := make(map[uint]RatingRes, len(ids))
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	var rows []struct {
+		ID      uint
+		Average float64
+		Count   int64
+	}
+	if err := db.Table("review").
+		Select(column+" AS id, AVG(rating) AS average, COUNT(*) AS count").
+		Where(column+" IN ?", ids).
+		Where("status = ?", "visible").
+		Group(column).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		res[row.ID] = RatingRes{
+			Average: math.Round(row.Average*100) / 100,
+			Count:   row.Count,
+		}
+	}
+
+	return res, nil
+}
+
+// GetRating is GetRatings for a single id, products without reviews get a zero rating.
+func GetRating(db *gorm.DB, column string, id uint) (*RatingRes, error) {
+	ratings, err := GetRatings(db, column, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rating := ratings[id]
+	return &rating, nil
+}
@@ -1,10 +1,16 @@
 package shop
 
 type ShopRes struct {
-	ID       int    `json:"id"`
-	NamaToko string `json:"nama_toko"`
-	UrlFoto  string `json:"url_foto"`
-	IdUser   *int   `json:"id_user,omitempty"`
+	ID       int        `json:"id"`
+	NamaToko string     `json:"nama_toko"`
+	UrlFoto  string     `json:"url_foto"`
+	IdUser   *int       `json:"id_user,omitempty"`
+	Rating   *RatingRes `json:"rating,omitempty"`
+}
+
+type RatingRes struct {
+	Average float64 `json:"average"`
+	Count   int64   `json:"count"`
 }
 
 type PaginatedShopRes struct {
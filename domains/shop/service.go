@@ -4,15 +4,17 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
-	"github.com/gofiber/fiber/v2"
+	fileutils "github.com/devanadindraa/Evermos-Backend/utils/file"
+	imageutils "github.com/devanadindraa/Evermos-Backend/utils/image"
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 	"gorm.io/gorm"
 )
 
@@ -26,12 +28,14 @@ type Service interface {
 type service struct {
 	authConfig config.Auth
 	db         *gorm.DB
+	store      storage.Store
 }
 
-func NewService(config *config.Config, db *gorm.DB) Service {
+func NewService(config *config.Config, db *gorm.DB, store storage.Store) Service {
 	return &service{
 		authConfig: config.Auth,
 		db:         db,
+		store:      store,
 	}
 }
 
@@ -47,12 +51,18 @@ func (s *service) GetMyShop(ctx context.Context) (res *ShopRes, err error) {
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, you don't have a shop")
 	}
 
+	rating, err := GetRating(s.db.WithContext(ctx), RATING_BY_SHOP, shop.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
 	IdUser := int(shop.IdUser)
 	result := &ShopRes{
 		ID:       int(shop.ID),
 		NamaToko: shop.NamaToko,
-		UrlFoto:  shop.UrlFoto,
+		UrlFoto:  s.store.URL(shop.UrlFoto),
 		IdUser:   &IdUser,
+		Rating:   rating,
 	}
 
 	return result, nil
@@ -65,10 +75,16 @@ func (s *service) GetShopByID(ctx context.Context, shopID string) (res *ShopRes,
 		return nil, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
 	}
 
+	rating, err := GetRating(s.db.WithContext(ctx), RATING_BY_SHOP, shop.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
 	result := &ShopRes{
 		ID:       int(shop.ID),
 		NamaToko: shop.NamaToko,
-		UrlFoto:  shop.UrlFoto,
+		UrlFoto:  s.store.URL(shop.UrlFoto),
+		Rating:   rating,
 	}
 
 	return result, nil
@@ -80,10 +96,10 @@ func (s *service) UpdateMyShop(ctx context.Context, input UpdateShopReq, shopID
 		return Toko{}, apierror.FromErr(err)
 	}
 	userID := token.Claims.ID
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(PERM_SHOP_MANAGE_ANY)
 
 	var shop Toko
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).First(&shop, "id = ? AND id_user = ?", shopID, userID).Error; err != nil {
 			return Toko{}, apierror.NewWarn(http.StatusNotFound, "Failed, shop not found / this is not your shop")
 		}
@@ -97,41 +113,50 @@ func (s *service) UpdateMyShop(ctx context.Context, input UpdateShopReq, shopID
 		shop.NamaToko = *input.NamaToko
 	}
 
+	oldFoto := shop.UrlFoto
 	if input.UrlFoto != nil {
-		if shop.UrlFoto != "" {
-			oldPath := filepath.Join(".", shop.UrlFoto)
-			if err := os.Remove(oldPath); err != nil && !os.IsNotExist(err) {
-				return Toko{}, fmt.Errorf("failed to remove old photo: %w", err)
-			}
+		variants, err := imageutils.Process(input.UrlFoto, imageutils.ShopVariants)
+		if err != nil {
+			return Toko{}, apierror.FromErr(err)
 		}
 
-		filename := fmt.Sprintf("shop_%d_%d_%d", shop.ID, time.Now().Unix(), shop.IdUser)
-		savePath := filepath.Join("uploads", "shops", filename)
+		name, err := fileutils.GenerateMediaName(strconv.Itoa(int(shop.ID)))
+		if err != nil {
+			return Toko{}, apierror.FromErr(fmt.Errorf("error generating image name: %v", err))
+		}
 
-		fiberCtx := ctx.Value(contextUtil.FiberCtxKey).(*fiber.Ctx)
-		if err := fiberCtx.SaveFile(input.UrlFoto, savePath); err != nil {
+		key := fmt.Sprintf("shops/%s.jpg", name)
+		if err := s.store.Put(ctx, key, variants[0].Data, "image/jpeg"); err != nil {
 			return Toko{}, apierror.FromErr(err)
 		}
 
-		shop.UrlFoto = "/" + savePath
+		shop.UrlFoto = key
 	}
 
 	shop.UpdatedAtDate = time.Now()
 
 	if err := s.db.WithContext(ctx).Save(&shop).Error; err != nil {
+		if shop.UrlFoto != oldFoto {
+			_ = s.store.Delete(ctx, shop.UrlFoto)
+		}
 		return Toko{}, apierror.FromErr(err)
 	}
 
+	// only drop the old photo once the new one is referenced
+	if oldFoto != "" && shop.UrlFoto != oldFoto {
+		if err := s.store.Delete(ctx, oldFoto); err != nil {
+			logger.Warn(ctx, "failed to remove old shop photo %s: %v", oldFoto, err)
+		}
+	}
+
+	shop.UrlFoto = s.store.URL(shop.UrlFoto)
 	return shop, nil
 }
 
 func (s *service) GetAllShop(ctx context.Context, filter *constants.FilterReq) (res *PaginatedShopRes, err error) {
+	// the storefront list is public, anonymous visitors get the public view
 	token, err := contextUtil.GetTokenClaims(ctx)
-	if err != nil {
-		return &PaginatedShopRes{}, apierror.FromErr(err)
-	}
-
-	isAdmin := token.Claims.IsAdmin
+	manageAny := err == nil && token.Can(PERM_SHOP_MANAGE_ANY)
 
 	var shops []Toko
 	var total int64
@@ -156,23 +181,37 @@ func (s *service) GetAllShop(ctx context.Context, filter *constants.FilterReq) (
 		return nil, apierror.FromErr(err)
 	}
 
+	shopIDs := make([]uint, 0, len(shops))
+	for _, cat := range shops {
+		shopIDs = append(shopIDs, cat.ID)
+	}
+
+	ratings, err := GetRatings(s.db.WithContext(ctx), RATING_BY_SHOP, shopIDs...)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
 	var result []ShopRes
-	if !isAdmin {
+	if !manageAny {
 		for _, cat := range shops {
+			rating := ratings[cat.ID]
 			result = append(result, ShopRes{
 				ID:       int(cat.ID),
 				NamaToko: cat.NamaToko,
-				UrlFoto:  cat.UrlFoto,
+				UrlFoto:  s.store.URL(cat.UrlFoto),
+				Rating:   &rating,
 			})
 		}
 	} else {
 		for _, cat := range shops {
 			IdUser := int(cat.IdUser)
+			rating := ratings[cat.ID]
 			result = append(result, ShopRes{
 				ID:       int(cat.ID),
 				NamaToko: cat.NamaToko,
-				UrlFoto:  cat.UrlFoto,
+				UrlFoto:  s.store.URL(cat.UrlFoto),
 				IdUser:   &IdUser,
+				Rating:   &rating,
 			})
 		}
 	}
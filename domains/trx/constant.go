@@ -0,0 +1,24 @@
+package trx
+
+import "github.com/devanadindraa/Evermos-Backend/domains/product"
+
+// Status of a single purchased line (detail_trx), every shop ships its own lines
+const (
+	STATUS_PENDING   = "pending"
+	STATUS_SHIPPED   = "shipped"
+	STATUS_DELIVERED = "delivered"
+	STATUS_CANCELLED = "cancelled"
+	STATUS_RETURNED  = "returned"
+)
+
+var statusTransitions = map[string][]string{
+	STATUS_PENDING:   {STATUS_SHIPPED, STATUS_CANCELLED},
+	STATUS_SHIPPED:   {STATUS_DELIVERED},
+	STATUS_DELIVERED: {STATUS_RETURNED},
+}
+
+// Stock movement booked when a line reaches the status, the goods go back on the shelf
+var restockOnStatus = map[string]string{
+	STATUS_CANCELLED: product.STOCK_CANCELLATION,
+	STATUS_RETURNED:  product.STOCK_RETURN,
+}
@@ -16,6 +16,7 @@ type Handler interface {
 	AddTrx(ctx *fiber.Ctx) error
 	GetTrxByID(ctx *fiber.Ctx) error
 	GetTrx(ctx *fiber.Ctx) error
+	UpdateDetailStatus(ctx *fiber.Ctx) error
 }
 
 type handler struct {
@@ -88,3 +89,32 @@ func (h *handler) GetTrx(ctx *fiber.Ctx) error {
 	respond.Success(ctx, http.StatusOK, "Succeed to GET all trx", result)
 	return nil
 }
+
+func (h *handler) UpdateDetailStatus(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	detailID := ctx.Params("id")
+	if detailID == "" {
+		respond.Error(ctx, fmt.Errorf("id is required"))
+		return nil
+	}
+
+	var input UpdateDetailStatusReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.UpdateDetailStatus(reqCtx, detailID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to PUT data", res)
+	return nil
+}
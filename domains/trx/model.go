@@ -1,6 +1,10 @@
 package trx
 
-import "time"
+import (
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/domains/product"
+)
 
 type Trx struct {
 	ID               uint      `gorm:"primaryKey"`
@@ -13,19 +17,8 @@ type Trx struct {
 	UpdatedAtDate    time.Time `gorm:"autoUpdateTime"`
 }
 
-type LogProduk struct {
-	ID            uint      `gorm:"primaryKey"`
-	IdProduk      uint      `gorm:"not null"`
-	NamaProduk    string    `json:"nama_produk"`
-	Slug          string    `json:"slug"`
-	HargaReseller string    `json:"harga_reseller"`
-	HargaKonsumen string    `json:"harga_konsumen"`
-	Deskripsi     string    `json:"deskripsi"`
-	CreatedAtDate time.Time `gorm:"autoCreateTime"`
-	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
-	IdToko        uint      `gorm:"not null"`
-	IdCategory    uint      `gorm:"not null"`
-}
+// LogProduk lives with the product versions it shares a table with
+type LogProduk = product.LogProduk
 
 type DetailTrx struct {
 	ID            uint      `gorm:"primaryKey"`
@@ -34,6 +27,7 @@ type DetailTrx struct {
 	IdToko        uint      `gorm:"not null"`
 	Kuantitas     int       `json:"kuantitas"`
 	HargaTotal    int       `json:"harga_total"`
+	Status        string    `json:"status"`
 	CreatedAtDate time.Time `gorm:"autoCreateTime"`
 	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
 }
@@ -42,9 +36,6 @@ func (Trx) TableName() string {
 	return "trx"
 }
 
-func (LogProduk) TableName() string {
-	return "log_produk"
-}
 func (DetailTrx) TableName() string {
 	return "detail_trx"
 }
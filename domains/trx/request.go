@@ -10,3 +10,7 @@ type DetailTrxReq struct {
 	ProdukId  int `json:"product_id"`
 	Kuantitas int `json:"kuantitas"`
 }
+
+type UpdateDetailStatusReq struct {
+	Status string `json:"status" validate:"required,oneof=shipped delivered cancelled returned"`
+}
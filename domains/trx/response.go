@@ -16,6 +16,8 @@ type TrxRes struct {
 }
 
 type DetailTrxRes struct {
+	ID         int                `json:"id"`
+	Status     string             `json:"status"`
 	Product    product.ProductRes `json:"product"`
 	Toko       *shop.ShopRes      `json:"toko"`
 	Kuantitas  int                `json:"kuantitas"`
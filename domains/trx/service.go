@@ -2,37 +2,46 @@ package trx
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/devanadindraa/Evermos-Backend/domains/address"
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
 	"github.com/devanadindraa/Evermos-Backend/domains/product"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
+	"github.com/devanadindraa/Evermos-Backend/domains/user"
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service interface {
 	AddTrx(ctx context.Context, input TrxReq) (res *Trx, err error)
 	GetTrxByID(ctx context.Context, trxID string) (*TrxRes, error)
 	GetTrx(ctx context.Context, filter *constants.FilterReq) (*PaginatedTrxRes, error)
+	UpdateDetailStatus(ctx context.Context, detailID string, input UpdateDetailStatusReq) (*DetailTrx, error)
 }
 
 type service struct {
 	authConfig config.Auth
 	db         *gorm.DB
+	store      storage.Store
 }
 
-func NewService(config *config.Config, db *gorm.DB) Service {
+func NewService(config *config.Config, db *gorm.DB, store storage.Store) Service {
 	return &service{
 		authConfig: config.Auth,
 		db:         db,
+		store:      store,
 	}
 }
 
@@ -44,6 +53,8 @@ func (s *service) AddTrx(ctx context.Context, input TrxReq) (*Trx, error) {
 	userID := uint(token.Claims.ID)
 
 	var trx *Trx
+	// one instant for the whole order, so every line is priced under the same rules
+	now := time.Now()
 
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
@@ -52,19 +63,62 @@ func (s *service) AddTrx(ctx context.Context, input TrxReq) (*Trx, error) {
 			return fmt.Errorf("invalid address")
 		}
 
+		// price every line once up front, the same snapshot is used for the total and the details
+		type line struct {
+			produk    product.Product
+			priceRule *product.PriceRule
+			flashItem *flashsale.ActiveItem
+			harga     int
+			kuantitas int
+		}
+
+		productIDs := make([]uint, 0, len(input.DetailTrx))
+		for _, item := range input.DetailTrx {
+			productIDs = append(productIDs, uint(item.ProdukId))
+		}
+		flashItems, err := flashsale.ActiveItems(tx, productIDs, now)
+		if err != nil {
+			return err
+		}
+
 		var totalHarga int
+		lines := make([]line, 0, len(input.DetailTrx))
 
 		for _, item := range input.DetailTrx {
+			if item.Kuantitas <= 0 {
+				return apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("kuantitas for product %d must be greater than 0", item.ProdukId))
+			}
+
 			var produk product.Product
 			if err := tx.First(&produk, item.ProdukId).Error; err != nil {
 				return fmt.Errorf("ID product %d not found: %w", item.ProdukId, err)
 			}
 
+			priceRule, err := product.ApplyEffectivePrice(tx, &produk, now)
+			if err != nil {
+				return err
+			}
+
 			harga, err := strconv.Atoi(produk.HargaKonsumen)
 			if err != nil {
 				return fmt.Errorf("ID product price %d not valid: %w", item.ProdukId, err)
 			}
+
+			var flashItem *flashsale.ActiveItem
+			if fi, ok := flashItems[produk.ID]; ok {
+				flashItem = &fi
+				harga = fi.Harga
+				produk.HargaKonsumen = strconv.Itoa(fi.Harga)
+			}
+
 			totalHarga += harga * item.Kuantitas
+			lines = append(lines, line{
+				produk:    produk,
+				priceRule: priceRule,
+				flashItem: flashItem,
+				harga:     harga,
+				kuantitas: item.Kuantitas,
+			})
 		}
 
 		kodeInvoice := fmt.Sprintf("INV-%d", time.Now().Unix())
@@ -82,25 +136,33 @@ func (s *service) AddTrx(ctx context.Context, input TrxReq) (*Trx, error) {
 			return fmt.Errorf("failed to save transaction: %w", err)
 		}
 
-		for _, item := range input.DetailTrx {
-			var produk product.Product
-			if err := tx.First(&produk, item.ProdukId).Error; err != nil {
-				return fmt.Errorf("ID product %d not found during insert log: %w", item.ProdukId, err)
+		for _, l := range lines {
+			var priceRuleID, flashItemID *uint
+			if l.priceRule != nil {
+				priceRuleID = &l.priceRule.ID
+			}
+			if l.flashItem != nil {
+				if err := flashsale.Reserve(tx, *l.flashItem, userID, trx.ID, l.kuantitas); err != nil {
+					return err
+				}
+				flashItemID = &l.flashItem.ID
 			}
 
-			harga, _ := strconv.Atoi(produk.HargaKonsumen)
-
+			produk := l.produk
 			logProduk := LogProduk{
-				IdProduk:      produk.ID,
-				NamaProduk:    produk.NamaProduk,
-				Slug:          produk.Slug,
-				HargaReseller: produk.HargaReseller,
-				HargaKonsumen: produk.HargaKonsumen,
-				Deskripsi:     produk.Deskripsi,
-				IdToko:        produk.IdToko,
-				IdCategory:    produk.IdCategory,
-				CreatedAtDate: time.Now(),
-				UpdatedAtDate: time.Now(),
+				IdProduk:         produk.ID,
+				NamaProduk:       produk.NamaProduk,
+				Slug:             produk.Slug,
+				HargaReseller:    produk.HargaReseller,
+				HargaKonsumen:    produk.HargaKonsumen,
+				Deskripsi:        produk.Deskripsi,
+				IdToko:           produk.IdToko,
+				IdCategory:       &produk.IdCategory,
+				Jenis:            product.LOG_CHECKOUT,
+				IdHargaTerjadwal: priceRuleID,
+				IdFlashSaleItem:  flashItemID,
+				CreatedAtDate:    time.Now(),
+				UpdatedAtDate:    time.Now(),
 			}
 
 			if err := tx.Create(&logProduk).Error; err != nil {
@@ -111,8 +173,9 @@ func (s *service) AddTrx(ctx context.Context, input TrxReq) (*Trx, error) {
 				IdTrx:         trx.ID,
 				IdLogProduk:   logProduk.ID,
 				IdToko:        logProduk.IdToko,
-				Kuantitas:     item.Kuantitas,
-				HargaTotal:    harga * item.Kuantitas,
+				Kuantitas:     l.kuantitas,
+				HargaTotal:    l.harga * l.kuantitas,
+				Status:        STATUS_PENDING,
 				CreatedAtDate: time.Now(),
 				UpdatedAtDate: time.Now(),
 			}
@@ -120,6 +183,16 @@ func (s *service) AddTrx(ctx context.Context, input TrxReq) (*Trx, error) {
 			if err := tx.Create(&detail).Error; err != nil {
 				return fmt.Errorf("failed to insert transaction details: %w", err)
 			}
+
+			if _, err := product.MoveStock(tx, product.StockChange{
+				ProductID: produk.ID,
+				Jenis:     product.STOCK_SALE,
+				Kuantitas: -l.kuantitas,
+				Referensi: fmt.Sprintf("detail_trx:%d", detail.ID),
+				ActorID:   &userID,
+			}); err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -138,10 +211,10 @@ func (s *service) GetTrxByID(ctx context.Context, trxID string) (*TrxRes, error)
 		return nil, err
 	}
 	userID := uint(token.Claims.ID)
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_ORDER_MANAGE_ANY)
 
 	var trx Trx
-	if !isAdmin {
+	if !manageAny {
 		if err := s.db.WithContext(ctx).
 			First(&trx, "id = ? AND id_user = ?", trxID, userID).Error; err != nil {
 			return nil, apierror.NewWarn(http.StatusNotFound, "Failed, trx not found")
@@ -178,15 +251,12 @@ func (s *service) GetTrxByID(ctx context.Context, trxID string) (*TrxRes, error)
 		s.db.WithContext(ctx).First(&shops, logProduk.IdToko)
 
 		var categorys category.Category
-		s.db.WithContext(ctx).First(&categorys, logProduk.IdCategory)
+		if logProduk.IdCategory != nil {
+			s.db.WithContext(ctx).First(&categorys, *logProduk.IdCategory)
+		}
 
 		var photos []product.Photo
-		s.db.WithContext(ctx).Where("id_produk = ?", logProduk.IdProduk).Find(&photos)
-
-		var photoURLs []string
-		for _, p := range photos {
-			photoURLs = append(photoURLs, p.Url)
-		}
+		s.db.WithContext(ctx).Scopes(product.OrderPhotos).Where("id_produk = ?", logProduk.IdProduk).Find(&photos)
 
 		productRes := &product.ProductRes{
 			ID:            int(logProduk.IdProduk),
@@ -199,15 +269,17 @@ func (s *service) GetTrxByID(ctx context.Context, trxID string) (*TrxRes, error)
 				ID:           int(categorys.ID),
 				NamaCategory: categorys.NamaCategory,
 			},
-			Photos: photoURLs,
+			Photos: product.ToPhotoRes(s.store, photos),
 		}
 
 		detailRes := DetailTrxRes{
+			ID:      int(d.ID),
+			Status:  d.Status,
 			Product: *productRes,
 			Toko: &shop.ShopRes{
 				ID:       int(logProduk.IdToko),
 				NamaToko: shops.NamaToko,
-				UrlFoto:  shops.UrlFoto,
+				UrlFoto:  s.store.URL(shops.UrlFoto),
 			},
 			Kuantitas:  d.Kuantitas,
 			HargaTotal: d.HargaTotal,
@@ -240,12 +312,12 @@ func (s *service) GetTrx(ctx context.Context, filter *constants.FilterReq) (*Pag
 		return nil, err
 	}
 	userID := uint(token.Claims.ID)
-	isAdmin := token.Claims.IsAdmin
+	manageAny := token.Can(user.PERM_ORDER_MANAGE_ANY)
 
 	var totalData int64
 	var trxs []Trx
 	var db *gorm.DB
-	if !isAdmin {
+	if !manageAny {
 		db = s.db.Model(&Trx{}).Where("id_user = ?", userID)
 	} else {
 		db = s.db.Model(&Trx{})
@@ -291,15 +363,12 @@ func (s *service) GetTrx(ctx context.Context, filter *constants.FilterReq) (*Pag
 			s.db.WithContext(ctx).First(&shops, logProduk.IdToko)
 
 			var categorys category.Category
-			s.db.WithContext(ctx).First(&categorys, logProduk.IdCategory)
+			if logProduk.IdCategory != nil {
+				s.db.WithContext(ctx).First(&categorys, *logProduk.IdCategory)
+			}
 
 			var photos []product.Photo
-			s.db.WithContext(ctx).Where("id_produk = ?", logProduk.IdProduk).Find(&photos)
-
-			var photoURLs []string
-			for _, p := range photos {
-				photoURLs = append(photoURLs, p.Url)
-			}
+			s.db.WithContext(ctx).Scopes(product.OrderPhotos).Where("id_produk = ?", logProduk.IdProduk).Find(&photos)
 
 			productRes := &product.ProductRes{
 				ID:            int(logProduk.IdProduk),
@@ -312,15 +381,17 @@ func (s *service) GetTrx(ctx context.Context, filter *constants.FilterReq) (*Pag
 					ID:           int(categorys.ID),
 					NamaCategory: categorys.NamaCategory,
 				},
-				Photos: photoURLs,
+				Photos: product.ToPhotoRes(s.store, photos),
 			}
 
 			detailRes := DetailTrxRes{
+				ID:      int(d.ID),
+				Status:  d.Status,
 				Product: *productRes,
 				Toko: &shop.ShopRes{
 					ID:       int(logProduk.IdToko),
 					NamaToko: shops.NamaToko,
-					UrlFoto:  shops.UrlFoto,
+					UrlFoto:  s.store.URL(shops.UrlFoto),
 				},
 				Kuantitas:  d.Kuantitas,
 				HargaTotal: d.HargaTotal,
@@ -351,3 +422,86 @@ func (s *service) GetTrx(ctx context.Context, filter *constants.FilterReq) (*Pag
 		Limit: int(filter.Limit),
 	}, nil
 }
+
+// UpdateDetailStatus moves one purchased line through its lifecycle.
+// Sellers ship, buyers confirm delivery, both may cancel a line that hasn't shipped.
+func (s *service) UpdateDetailStatus(ctx context.Context, detailID string, input UpdateDetailStatusReq) (*DetailTrx, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := uint(token.Claims.ID)
+	manageAny := token.Can(user.PERM_ORDER_MANAGE_ANY)
+
+	var detail DetailTrx
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&detail, "id = ?", detailID).Error; err != nil {
+			return apierror.NewWarn(http.StatusNotFound, "Failed, trx detail not found")
+		}
+
+		var trx Trx
+		if err := tx.First(&trx, "id = ?", detail.IdTrx).Error; err != nil {
+			return apierror.NewWarn(http.StatusNotFound, "Failed, trx not found")
+		}
+
+		var toko shop.Toko
+		if err := tx.First(&toko, "id = ?", detail.IdToko).Error; err != nil {
+			return apierror.NewWarn(http.StatusNotFound, "Failed, shop not found")
+		}
+
+		isBuyer := trx.IdUser == userID
+		isSeller := toko.IdUser == userID
+
+		if !slices.Contains(statusTransitions[detail.Status], input.Status) {
+			return apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("Can't change status from %s to %s", detail.Status, input.Status))
+		}
+
+		allowed := manageAny
+		switch input.Status {
+		case STATUS_SHIPPED:
+			allowed = allowed || isSeller
+		case STATUS_DELIVERED, STATUS_CANCELLED:
+			allowed = allowed || isSeller || isBuyer
+		case STATUS_RETURNED:
+			// the seller confirms the goods actually came back
+			allowed = allowed || isSeller
+		}
+		if !allowed {
+			return apierror.NewWarn(http.StatusForbidden, "You are not allowed to change this trx detail")
+		}
+
+		if jenis, ok := restockOnStatus[input.Status]; ok {
+			var logProduk LogProduk
+			if err := tx.First(&logProduk, "id = ?", detail.IdLogProduk).Error; err != nil {
+				return err
+			}
+
+			if logProduk.IdFlashSaleItem != nil {
+				if err := flashsale.Release(tx, *logProduk.IdFlashSaleItem, detail.IdTrx, detail.Kuantitas); err != nil {
+					return err
+				}
+			}
+
+			_, err := product.MoveStock(tx, product.StockChange{
+				ProductID: logProduk.IdProduk,
+				Jenis:     jenis,
+				Kuantitas: detail.Kuantitas,
+				Referensi: fmt.Sprintf("detail_trx:%d", detail.ID),
+				ActorID:   &userID,
+			})
+			// a deleted product has no stock left to put the goods back into
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		detail.Status = input.Status
+		detail.UpdatedAtDate = time.Now()
+		return tx.Save(&detail).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &detail, nil
+}
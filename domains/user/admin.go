@@ -0,0 +1,229 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"gorm.io/gorm"
+)
+
+func (s *service) ListUsers(ctx context.Context, filter *constants.FilterReq, input ListUsersReq) (*PaginatedUserRes, error) {
+	var users []User
+	var total int64
+
+	query := s.db.WithContext(ctx).Model(&User{})
+
+	if filter.Keyword != "" {
+		keyword := "%" + filter.Keyword + "%"
+		query = query.Where("nama LIKE ? OR email LIKE ? OR notelp LIKE ?", keyword, keyword, keyword)
+	}
+
+	switch input.Status {
+	case USER_STATUS_ACTIVE:
+		query = query.Where("suspended_at IS NULL")
+	case USER_STATUS_SUSPENDED:
+		query = query.Where("suspended_at IS NOT NULL")
+	}
+
+	if input.Role != "" {
+		query = query.Where("id IN (?)", s.db.Table("user_role").
+			Select("user_role.id_user").
+			Joins("JOIN role ON role.id = user_role.id_role").
+			Where("role.kode = ?", input.Role))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+
+	if err := query.
+		Order(fmt.Sprintf("%s %s", filter.OrderBy, filter.SortOrder)).
+		Limit(int(filter.Limit)).
+		Offset(int(offset)).
+		Find(&users).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	roles, err := loadUserRoles(s.db.WithContext(ctx), userIDs...)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := make([]UserRes, 0, len(users))
+	for _, user := range users {
+		result = append(result, toUserRes(user, roles[user.ID]))
+	}
+
+	return &PaginatedUserRes{
+		Page:  int(filter.Page),
+		Limit: int(filter.Limit),
+		Total: int(total),
+		Data:  result,
+	}, nil
+}
+
+func (s *service) GetUser(ctx context.Context, userID string) (*UserRes, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRes(ctx, user.ID)
+}
+
+func (s *service) PromoteUser(ctx context.Context, userID string) (*UserRes, error) {
+	res, err := s.AssignRole(ctx, userID, AssignRoleReq{Role: ROLE_ADMIN})
+	if err != nil {
+		return nil, err
+	}
+	return s.userRes(ctx, uint(res.IdUser))
+}
+
+func (s *service) DemoteUser(ctx context.Context, userID string) (*UserRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == uint(token.Claims.ID) {
+		return nil, apierror.NewWarn(http.StatusConflict, ErrDemoteSelf)
+	}
+
+	if _, err := s.RemoveRole(ctx, userID, ROLE_ADMIN); err != nil {
+		return nil, err
+	}
+	return s.userRes(ctx, user.ID)
+}
+
+// SuspendUser blocks an account: its refresh tokens are revoked, logins are refused and
+// the middlewares reject its access tokens once the access cache entry is gone.
+func (s *service) SuspendUser(ctx context.Context, userID string, input SuspendUserReq) (*UserRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == uint(token.Claims.ID) {
+		return nil, apierror.NewWarn(http.StatusConflict, ErrSuspendSelf)
+	}
+	// support may suspend customers, suspending an admin takes the right to change admins
+	if user.IsAdmin {
+		missing, err := s.HasPermissions(ctx, uint(token.Claims.ID), PERM_ROLE_MANAGE)
+		if err != nil {
+			return nil, err
+		}
+		if missing != "" {
+			return nil, apierror.NewWarn(http.StatusForbidden, fmt.Sprintf(ErrMissingPermission, missing))
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"suspended_at":   time.Now(),
+			"alasan_suspend": input.Alasan,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx, "id_user = ?", user.ID)
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	s.access.forget(user.ID)
+	return s.userRes(ctx, user.ID)
+}
+
+func (s *service) UnsuspendUser(ctx context.Context, userID string) (*UserRes, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&User{}).Where("id = ?", user.ID).Updates(map[string]any{
+		"suspended_at":   nil,
+		"alasan_suspend": "",
+	}).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	s.access.forget(user.ID)
+	return s.userRes(ctx, user.ID)
+}
+
+func (s *service) userRes(ctx context.Context, userID uint) (*UserRes, error) {
+	var user User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	roles, err := loadUserRoles(s.db.WithContext(ctx), user.ID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res := toUserRes(user, roles[user.ID])
+	return &res, nil
+}
+
+// loadUserRoles returns the role codes of every user in userIDs.
+func loadUserRoles(db *gorm.DB, userIDs ...uint) (map[uint][]string, error) {
+	res := map[uint][]string{}
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	var rows []struct {
+		IdUser uint
+		Kode   string
+	}
+	if err := db.Table("user_role").
+		Select("user_role.id_user, role.kode").
+		Joins("JOIN role ON role.id = user_role.id_role").
+		Where("user_role.id_user IN ?", userIDs).
+		Order("role.kode").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		res[row.IdUser] = append(res[row.IdUser], row.Kode)
+	}
+	return res, nil
+}
+
+func toUserRes(user User, roles []string) UserRes {
+	if roles == nil {
+		roles = []string{}
+	}
+	return UserRes{
+		ID:            int(user.ID),
+		Nama:          user.Nama,
+		Notelp:        user.Notelp,
+		Email:         user.Email,
+		IsAdmin:       user.IsAdmin,
+		Roles:         roles,
+		SuspendedAt:   user.SuspendedAt,
+		AlasanSuspend: user.AlasanSuspend,
+		CreatedAtDate: user.CreatedAtDate,
+	}
+}
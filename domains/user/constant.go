@@ -1,6 +1,100 @@
 package user
 
+import "github.com/devanadindraa/Evermos-Backend/domains/shop"
+
 const (
 	ErrInvalidCredentials = "invalid username or password"
 	ErrInvalidCurPassword = "Current Password Invalid"
+	ErrTokenRevoked       = "Token has been revoked"
+	ErrTokenWithoutJti    = "Token has no jti, please log in again"
+	ErrInvalidRefresh     = "Refresh token is invalid or expired"
+	ErrRefreshReused      = "Refresh token was already used, please log in again"
+	ErrUserNotFound       = "User not found"
+	ErrRoleNotFound       = "Role %s not found"
+	ErrLastAdmin          = "The last admin can't lose the admin role"
+	ErrMissingPermission  = "Access denied: missing permission %s"
+	ErrAccountSuspended   = "Account is suspended"
+	ErrSuspendSelf        = "You can't suspend your own account"
+	ErrDemoteSelf         = "You can't remove your own admin role"
+	ErrInvalidOTP         = "Code is invalid or expired"
+	ErrOTPTooSoon         = "A code was sent recently, please wait before requesting another"
+	ErrUnknownContact     = "Unknown contact %s, use phone or email"
+	ErrAlreadyVerified    = "Your %s is already verified"
+	ErrNoEmail            = "Your account has no email address"
+	ErrPhoneNotVerified   = "Please verify your phone number first"
+	ErrTwoFactorEnabled   = "Two-factor authentication is already enabled"
+	ErrTwoFactorDisabled  = "Two-factor authentication is not enabled"
+	ErrNoEnrollment       = "Start the enrollment first"
+	ErrInvalidTwoFactor   = "Two-factor code is invalid"
+	ErrInvalidChallenge   = "Login challenge is invalid or expired, please log in again"
+	ErrTwoFactorRequired  = "Two-factor authentication is required, enable it and log in again"
+	ErrLoginLocked        = "Too many failed login attempts, try again in %d seconds"
+	ErrSessionNotFound    = "Session not found"
+)
+
+const (
+	ROLE_ADMIN             = "admin"
+	ROLE_CATALOG_MODERATOR = "catalog_moderator"
+	ROLE_FINANCE           = "finance"
+	ROLE_SUPPORT           = "support"
+	ROLE_SELLER            = "seller"
+	ROLE_RESELLER          = "reseller"
+	ROLE_BUYER             = "buyer"
+)
+
+// Permissions checked by mw.Require or, for acting on other users' data, by the services
+// through Token.Can. Which role holds which is kept in role_permission
+const (
+	PERM_CATEGORY_MANAGE    = "category.manage"
+	PERM_PRODUCT_MODERATE   = "product.moderate"
+	PERM_REVIEW_MODERATE    = "review.moderate"
+	PERM_FLASH_SALE_MANAGE  = "flash_sale.manage"
+	PERM_ROLE_MANAGE        = "role.manage"
+	PERM_USER_MANAGE        = "user.manage"
+	PERM_PRODUCT_MANAGE_ANY = "product.manage_any"
+	PERM_ORDER_MANAGE_ANY   = "order.manage_any"
+	PERM_SHOP_MANAGE_ANY    = shop.PERM_SHOP_MANAGE_ANY
+
+	// held by the seller, reseller and buyer roles
+	PERM_PRODUCT_SELL = "product.sell"
+	PERM_SHOP_MANAGE  = "shop.manage"
+	PERM_ORDER_PLACE  = "order.place"
+	PERM_REVIEW_WRITE = "review.write"
+)
+
+// BASE_PERMISSIONS are those of the seller, reseller and buyer roles. Unlike staff
+// permissions they don't need the second factor TwoFactor.RequireForAdmins asks for.
+var BASE_PERMISSIONS = []string{PERM_PRODUCT_SELL, PERM_SHOP_MANAGE, PERM_ORDER_PLACE, PERM_REVIEW_WRITE}
+
+const (
+	USER_STATUS_ACTIVE    = "active"
+	USER_STATUS_SUSPENDED = "suspended"
+)
+
+const (
+	OTP_RESET_PASSWORD = "reset_password"
+	OTP_VERIFY_PHONE   = "verify_phone"
+	OTP_VERIFY_EMAIL   = "verify_email"
+	OTP_RECOVERY_CODE  = "recovery_code"
+	OTP_LENGTH         = 6
+)
+
+// contacts that can be verified, as used in /user/verify/:contact
+const (
+	CONTACT_PHONE = "phone"
+	CONTACT_EMAIL = "email"
+)
+
+// login_throttle keys
+const (
+	THROTTLE_ACCOUNT = "notelp:"
+	THROTTLE_IP      = "ip:"
+)
+
+// login_audit results
+const (
+	AUDIT_FAILED   = "failed"
+	AUDIT_LOCKED   = "locked"
+	AUDIT_LOCKOUT  = "lockout"
+	AUDIT_UNLOCKED = "unlocked"
 )
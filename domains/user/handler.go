@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/common"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
 	"github.com/devanadindraa/Evermos-Backend/utils/respond"
 	"github.com/go-playground/validator/v10"
@@ -14,10 +15,38 @@ import (
 type Handler interface {
 	Login(ctx *fiber.Ctx) error
 	VerifyToken(ctx *fiber.Ctx) error
+	LoginTwoFactor(ctx *fiber.Ctx) error
+	Refresh(ctx *fiber.Ctx) error
 	Logout(ctx *fiber.Ctx) error
+	ForgotPassword(ctx *fiber.Ctx) error
+	ResetPassword(ctx *fiber.Ctx) error
 	Register(ctx *fiber.Ctx) error
 	UpdateProfile(ctx *fiber.Ctx) error
 	GetProfile(ctx *fiber.Ctx) error
+	GetSessions(ctx *fiber.Ctx) error
+	RevokeSession(ctx *fiber.Ctx) error
+	RevokeAllSessions(ctx *fiber.Ctx) error
+	SendVerification(ctx *fiber.Ctx) error
+	VerifyContact(ctx *fiber.Ctx) error
+	GetTwoFactor(ctx *fiber.Ctx) error
+	EnrollTwoFactor(ctx *fiber.Ctx) error
+	EnableTwoFactor(ctx *fiber.Ctx) error
+	DisableTwoFactor(ctx *fiber.Ctx) error
+	RegenerateRecoveryCodes(ctx *fiber.Ctx) error
+	JWKS(ctx *fiber.Ctx) error
+	GetRoles(ctx *fiber.Ctx) error
+	GetUserRoles(ctx *fiber.Ctx) error
+	AssignRole(ctx *fiber.Ctx) error
+	RemoveRole(ctx *fiber.Ctx) error
+	ListUsers(ctx *fiber.Ctx) error
+	GetUser(ctx *fiber.Ctx) error
+	PromoteUser(ctx *fiber.Ctx) error
+	DemoteUser(ctx *fiber.Ctx) error
+	SuspendUser(ctx *fiber.Ctx) error
+	UnsuspendUser(ctx *fiber.Ctx) error
+	UnlockUser(ctx *fiber.Ctx) error
+	UnlockIP(ctx *fiber.Ctx) error
+	ListLoginAudit(ctx *fiber.Ctx) error
 }
 
 type handler struct {
@@ -44,6 +73,8 @@ func (h *handler) Login(ctx *fiber.Ctx) error {
 		respond.Error(ctx, apierror.FromErr(err))
 		return nil
 	}
+	input.IP = ctx.IP()
+	input.UserAgent = ctx.Get(fiber.HeaderUserAgent)
 
 	res, err := h.service.Login(ctx.Context(), input)
 	if err != nil {
@@ -60,20 +91,117 @@ func (h *handler) VerifyToken(ctx *fiber.Ctx) error {
 	return nil
 }
 
+func (h *handler) LoginTwoFactor(ctx *fiber.Ctx) error {
+	var input LoginTwoFactorReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	input.IP = ctx.IP()
+	input.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
+	res, err := h.service.LoginTwoFactor(ctx.Context(), input)
+	if err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) Refresh(ctx *fiber.Ctx) error {
+	var input RefreshReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	input.IP = ctx.IP()
+	input.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
+	res, err := h.service.Refresh(ctx.Context(), input)
+	if err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
 func (h *handler) Logout(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
 
-	token, err := contextUtil.GetTokenClaims(ctx.Context())
+	token, err := contextUtil.GetTokenClaims(reqCtx)
 	if err != nil {
 		respond.Error(ctx, apierror.FromErr(err))
 		return nil
 	}
 
 	input := LogoutReq{
-		Token:   token.Token,
+		Jti:     token.Claims.RegisteredClaims.ID,
+		IdUser:  uint(token.Claims.ID),
+		Family:  token.Claims.Family,
 		Expires: token.Claims.ExpiresAt.Time,
 	}
 
-	res, err := h.service.Logout(ctx.Context(), input)
+	res, err := h.service.Logout(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) ForgotPassword(ctx *fiber.Ctx) error {
+	var input ForgotPasswordReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.ForgotPassword(ctx.Context(), input)
+	if err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) ResetPassword(ctx *fiber.Ctx) error {
+	var input ResetPasswordReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.ResetPassword(ctx.Context(), input)
 	if err != nil {
 		respond.Error(ctx, apierror.FromErr(err))
 		return nil
@@ -125,6 +253,11 @@ func (h *handler) UpdateProfile(ctx *fiber.Ctx) error {
 		return nil
 	}
 
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
 	res, err := h.service.UpdateProfile(reqCtx, input)
 	if err != nil {
 		respond.Error(ctx, err)
@@ -147,3 +280,407 @@ func (h *handler) GetProfile(ctx *fiber.Ctx) error {
 	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
 	return nil
 }
+
+func (h *handler) GetSessions(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.GetSessions(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) RevokeSession(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.RevokeSession(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) RevokeAllSessions(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.RevokeAllSessions(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) SendVerification(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.SendVerification(reqCtx, ctx.Params("contact"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) VerifyContact(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input VerifyContactReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.VerifyContact(reqCtx, ctx.Params("contact"), input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) GetTwoFactor(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.GetTwoFactor(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) EnrollTwoFactor(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.EnrollTwoFactor(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) EnableTwoFactor(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input TwoFactorCodeReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.EnableTwoFactor(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) DisableTwoFactor(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input DisableTwoFactorReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.DisableTwoFactor(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input TwoFactorCodeReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.RegenerateRecoveryCodes(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+// JWKS is served as a plain JWK Set, not wrapped in the API envelope, so standard
+// JWT libraries of other services can consume it.
+func (h *handler) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set("Cache-Control", "public, max-age=300")
+	return ctx.Status(http.StatusOK).JSON(h.service.JWKS())
+}
+
+func (h *handler) GetRoles(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.GetRoles(reqCtx)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) GetUserRoles(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.GetUserRoles(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) AssignRole(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input AssignRoleReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AssignRole(reqCtx, ctx.Params("id"), input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) RemoveRole(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.RemoveRole(reqCtx, ctx.Params("id"), ctx.Params("role"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) ListUsers(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	filter, err := common.GetMetaData(ctx, h.validate, "id", "nama", "created_at_date")
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	var input ListUsersReq
+	if err := ctx.QueryParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.ListUsers(reqCtx, filter, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) GetUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.GetUser(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) PromoteUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.PromoteUser(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) DemoteUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.DemoteUser(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) SuspendUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input SuspendUserReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.SuspendUser(reqCtx, ctx.Params("id"), input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) UnsuspendUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.UnsuspendUser(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) UnlockUser(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.UnlockUser(reqCtx, ctx.Params("id"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) UnlockIP(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	res, err := h.service.UnlockIP(reqCtx, ctx.Params("ip"))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", res)
+	return nil
+}
+
+func (h *handler) ListLoginAudit(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	filter, err := common.GetMetaData(ctx, h.validate, "created_at_date", "id")
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	var input LoginAuditReq
+	if err := ctx.QueryParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.ListLoginAudit(reqCtx, filter, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
@@ -0,0 +1,287 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type throttleKey struct {
+	kunci       string
+	maxFailures int
+}
+
+// throttleKeys returns the account key before the IP key, rows are always locked in
+// this order.
+func (s *service) throttleKeys(notelp, ip string) []throttleKey {
+	lockout := s.authConfig.Lockout
+	return []throttleKey{
+		{kunci: THROTTLE_ACCOUNT + notelp, maxFailures: lockout.AccountMaxFailures},
+		{kunci: THROTTLE_IP + ip, maxFailures: lockout.IPMaxFailures},
+	}
+}
+
+// beginLogin counts an attempt against the account and the IP before the credentials
+// are checked, so parallel guesses are throttled as well. It refuses while either is
+// locked or backing off. finishLogin settles the count once the outcome is known.
+func (s *service) beginLogin(ctx context.Context, notelp, ip string) error {
+	var wait time.Duration
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		rows := make([]LoginThrottle, 0, 2)
+
+		for _, key := range s.throttleKeys(notelp, ip) {
+			row, err := lockThrottle(tx, key.kunci)
+			if err != nil {
+				return err
+			}
+			s.expireThrottle(row, now)
+
+			if row.LockedUntil != nil {
+				wait = max(wait, row.LockedUntil.Sub(now))
+			} else if row.TerakhirGagal != nil {
+				if until := row.TerakhirGagal.Add(s.backoff(row.Gagal)); now.Before(until) {
+					wait = max(wait, until.Sub(now))
+				}
+			}
+			rows = append(rows, *row)
+		}
+
+		for i := range rows {
+			if wait == 0 {
+				rows[i].Gagal++
+				rows[i].TerakhirGagal = &now
+			}
+			if err := tx.Save(&rows[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		s.audit(ctx, LoginAudit{
+			Notelp:     notelp,
+			Ip:         ip,
+			Hasil:      AUDIT_LOCKED,
+			Keterangan: fmt.Sprintf("retry in %ds", seconds),
+		})
+		return apierror.NewWarn(http.StatusTooManyRequests, fmt.Sprintf(ErrLoginLocked, seconds))
+	}
+	return nil
+}
+
+// finishLogin settles an attempt counted by beginLogin. A success clears the account
+// and takes the attempt back from the IP, a failure is audited and locks the account
+// or IP once it reached its limit.
+func (s *service) finishLogin(ctx context.Context, notelp, ip string, userID *uint, success bool, reason string) {
+	keys := s.throttleKeys(notelp, ip)
+
+	if success {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&LoginThrottle{}).Where("kunci = ?", keys[0].kunci).Updates(map[string]any{
+				"gagal":          0,
+				"terakhir_gagal": nil,
+				"locked_until":   nil,
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&LoginThrottle{}).Where("kunci = ? AND gagal > 0", keys[1].kunci).
+				Update("gagal", gorm.Expr("gagal - 1")).Error
+		})
+		if err != nil {
+			logger.Error(ctx, "failed to reset login throttle of %s: %v", notelp, err)
+		}
+		return
+	}
+
+	var locked []string
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for _, key := range keys {
+			row, err := lockThrottle(tx, key.kunci)
+			if err != nil {
+				return err
+			}
+			if row.LockedUntil != nil || row.Gagal < key.maxFailures {
+				continue
+			}
+
+			until := now.Add(s.authConfig.Lockout.LockoutDuration)
+			if err := tx.Model(row).Update("locked_until", until).Error; err != nil {
+				return err
+			}
+			locked = append(locked, key.kunci)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error(ctx, "failed to update login throttle of %s: %v", notelp, err)
+	}
+
+	s.audit(ctx, LoginAudit{IdUser: userID, Notelp: notelp, Ip: ip, Hasil: AUDIT_FAILED, Keterangan: reason})
+	for _, kunci := range locked {
+		s.audit(ctx, LoginAudit{
+			IdUser:     userID,
+			Notelp:     notelp,
+			Ip:         ip,
+			Hasil:      AUDIT_LOCKOUT,
+			Keterangan: fmt.Sprintf("%s locked for %s", kunci, s.authConfig.Lockout.LockoutDuration),
+		})
+	}
+}
+
+// backoff is the wait after the given number of failures: none up to BackoffAfter,
+// then BackoffBase doubling with every failure, at most BackoffMax.
+func (s *service) backoff(failures int) time.Duration {
+	lockout := s.authConfig.Lockout
+	if failures < lockout.BackoffAfter {
+		return 0
+	}
+
+	wait := lockout.BackoffBase
+	for i := lockout.BackoffAfter; i < failures && wait < lockout.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, lockout.BackoffMax)
+}
+
+// expireThrottle forgets an ended lockout and failures older than FailureWindow.
+func (s *service) expireThrottle(row *LoginThrottle, now time.Time) {
+	if row.LockedUntil != nil && !now.Before(*row.LockedUntil) {
+		row.Gagal, row.LockedUntil, row.TerakhirGagal = 0, nil, nil
+	}
+	if row.TerakhirGagal != nil && now.Sub(*row.TerakhirGagal) > s.authConfig.Lockout.FailureWindow {
+		row.Gagal, row.TerakhirGagal = 0, nil
+	}
+}
+
+func lockThrottle(tx *gorm.DB, kunci string) (*LoginThrottle, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&LoginThrottle{Kunci: kunci}).Error; err != nil {
+		return nil, err
+	}
+
+	var row LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, "kunci = ?", kunci).Error; err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// audit records a login event, a failing insert is logged but doesn't fail the request.
+func (s *service) audit(ctx context.Context, entry LoginAudit) {
+	entry.CreatedAtDate = time.Now()
+	logger.Warn(ctx, "login %s: notelp=%s ip=%s %s", entry.Hasil, entry.Notelp, entry.Ip, entry.Keterangan)
+
+	if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+		logger.Error(ctx, "failed to write login audit: %v", err)
+	}
+}
+
+func (s *service) UnlockUser(ctx context.Context, userID string) (*UnlockRes, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.unlock(ctx, THROTTLE_ACCOUNT+user.Notelp, LoginAudit{IdUser: &user.ID, Notelp: user.Notelp})
+}
+
+func (s *service) UnlockIP(ctx context.Context, ip string) (*UnlockRes, error) {
+	return s.unlock(ctx, THROTTLE_IP+ip, LoginAudit{Ip: ip})
+}
+
+func (s *service) unlock(ctx context.Context, kunci string, entry LoginAudit) (*UnlockRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	result := s.db.WithContext(ctx).Where("kunci = ?", kunci).Delete(&LoginThrottle{})
+	if result.Error != nil {
+		return nil, apierror.FromErr(result.Error)
+	}
+
+	entry.Hasil = AUDIT_UNLOCKED
+	entry.Keterangan = kunci
+	entry.IdPelaku = &actorID
+	s.audit(ctx, entry)
+
+	return &UnlockRes{
+		Kunci:    kunci,
+		Unlocked: result.RowsAffected > 0,
+	}, nil
+}
+
+func (s *service) ListLoginAudit(ctx context.Context, filter *constants.FilterReq, input LoginAuditReq) (*PaginatedLoginAuditRes, error) {
+	var entries []LoginAudit
+	var total int64
+
+	query := s.db.WithContext(ctx).Model(&LoginAudit{})
+
+	if filter.Keyword != "" {
+		query = query.Where("notelp LIKE ?", "%"+filter.Keyword+"%")
+	}
+	if input.Hasil != "" {
+		query = query.Where("hasil = ?", input.Hasil)
+	}
+	if input.Ip != "" {
+		query = query.Where("ip = ?", input.Ip)
+	}
+	if filter.StartCreatedAt != nil {
+		query = query.Where("created_at_date >= ?", filter.StartCreatedAt)
+	}
+	if filter.EndCreatedAt != nil {
+		query = query.Where("created_at_date <= ?", filter.EndCreatedAt)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+
+	if err := query.
+		Order(fmt.Sprintf("%s %s", filter.OrderBy, filter.SortOrder)).
+		Limit(int(filter.Limit)).
+		Offset(int(offset)).
+		Find(&entries).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	result := make([]LoginAuditRes, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, LoginAuditRes{
+			ID:            int(entry.ID),
+			IdUser:        entry.IdUser,
+			Notelp:        entry.Notelp,
+			Ip:            entry.Ip,
+			Hasil:         entry.Hasil,
+			Keterangan:    entry.Keterangan,
+			IdPelaku:      entry.IdPelaku,
+			CreatedAtDate: entry.CreatedAtDate,
+		})
+	}
+
+	return &PaginatedLoginAuditRes{
+		Page:  int(filter.Page),
+		Limit: int(filter.Limit),
+		Total: int(total),
+		Data:  result,
+	}, nil
+}
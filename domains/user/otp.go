@@ -0,0 +1,138 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sendOTP creates a code for tujuan, replacing any earlier one, and sends it to the
+// user through kanal. It refuses with ErrOTPTooSoon within ResendInterval of the last code.
+func (s *service) sendOTP(ctx context.Context, user User, tujuan, kanal, to, subject string) error {
+	otpConf := s.authConfig.OTP
+	code, err := newOTPCode()
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// lock the user row so two requests can't both pass the resend check
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&User{}, "id = ?", user.ID).Error; err != nil {
+			return err
+		}
+
+		var recent int64
+		if err := tx.Model(&OTP{}).
+			Where("id_user = ? AND tujuan = ? AND created_at_date > ?", user.ID, tujuan, time.Now().Add(-otpConf.ResendInterval)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return apierror.NewWarn(http.StatusTooManyRequests, ErrOTPTooSoon)
+		}
+
+		now := time.Now()
+		if err := tx.Model(&OTP{}).
+			Where("id_user = ? AND tujuan = ? AND used_at IS NULL AND expires > ?", user.ID, tujuan, now).
+			Update("expires", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&OTP{
+			IdUser:        user.ID,
+			Tujuan:        tujuan,
+			Kanal:         kanal,
+			CodeHash:      s.hashOTP(user.ID, tujuan, code),
+			Expires:       now.Add(otpConf.ExpireIn),
+			CreatedAtDate: now,
+		}).Error
+	})
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+
+	if err := s.notifier.Send(ctx, notifier.Message{
+		Channel: kanal,
+		To:      to,
+		Subject: subject,
+		Body:    fmt.Sprintf("Kode verifikasi Anda: %s. Berlaku %d menit, jangan berikan kepada siapa pun.", code, int(otpConf.ExpireIn.Minutes())),
+	}); err != nil {
+		return apierror.FromErr(fmt.Errorf("failed to send code: %w", err))
+	}
+	return nil
+}
+
+// useOTP checks code against the live code of tujuan and, when it matches, marks it
+// used and runs apply in the same transaction. A wrong code counts as an attempt, after
+// MaxAttempts the code stops working and a new one has to be requested.
+func (s *service) useOTP(ctx context.Context, userID uint, tujuan, code string, apply func(tx *gorm.DB) error) error {
+	wrong := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var otp OTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id_user = ? AND tujuan = ? AND used_at IS NULL", userID, tujuan).
+			Order("id DESC").
+			First(&otp).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusBadRequest, ErrInvalidOTP)
+			}
+			return err
+		}
+
+		if !otp.Expires.After(time.Now()) || otp.Attempts >= s.authConfig.OTP.MaxAttempts {
+			return apierror.NewWarn(http.StatusBadRequest, ErrInvalidOTP)
+		}
+
+		if !hmac.Equal([]byte(otp.CodeHash), []byte(s.hashOTP(userID, tujuan, code))) {
+			// committed on purpose, the attempt has to count even though the request fails
+			wrong = true
+			return tx.Model(&otp).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if err := tx.Model(&otp).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return apply(tx)
+	})
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+	if wrong {
+		return apierror.NewWarn(http.StatusBadRequest, ErrInvalidOTP)
+	}
+	return nil
+}
+
+// hashOTP keys the hash with the encryption key, a leaked otp table alone doesn't allow
+// trying the million possible codes offline.
+func (s *service) hashOTP(userID uint, tujuan, code string) string {
+	mac := hmac.New(sha256.New, []byte("otp:"+s.authConfig.EncryptionKey))
+	fmt.Fprintf(mac, "%d:%s:%s", userID, tujuan, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newOTPCode() (string, error) {
+	max := big.NewInt(1)
+	for range OTP_LENGTH {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", OTP_LENGTH, n), nil
+}
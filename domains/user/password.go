@@ -0,0 +1,98 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
+	"gorm.io/gorm"
+)
+
+// ForgotPassword sends a reset code to the phone number or email of the account. The
+// answer doesn't tell whether the account exists or whether a code was sent.
+func (s *service) ForgotPassword(ctx context.Context, input ForgotPasswordReq) (*OTPSentRes, error) {
+	kanal, to := notifier.CHANNEL_SMS, input.NoTelp
+	if input.NoTelp == "" {
+		kanal, to = notifier.CHANNEL_EMAIL, input.Email
+	}
+	res := &OTPSentRes{
+		Kanal:    kanal,
+		ExpireIn: int(s.authConfig.OTP.ExpireIn.Seconds()),
+	}
+
+	user, err := s.findAccount(ctx, input.NoTelp, input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, nil
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	if err := s.sendOTP(ctx, *user, OTP_RESET_PASSWORD, kanal, to, "Reset kata sandi"); err != nil {
+		if apierror.GetApiErrors(err).Code == http.StatusTooManyRequests {
+			logger.Info(ctx, "reset code for user %d not sent, one was sent recently", user.ID)
+			return res, nil
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ResetPassword sets a new password with a code from ForgotPassword. Every session of
+// the account ends: refresh tokens are revoked and older access tokens are refused.
+func (s *service) ResetPassword(ctx context.Context, input ResetPasswordReq) (*ResetPasswordRes, error) {
+	user, err := s.findAccount(ctx, input.NoTelp, input.Email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusBadRequest, ErrInvalidOTP)
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	hashedPassword, err := hashPassword(input.KataSandiBaru)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	err = s.useOTP(ctx, user.ID, OTP_RESET_PASSWORD, input.Kode, func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"kata_sandi":         hashedPassword,
+			"tokens_valid_after": time.Now(),
+			"updated_at_date":    time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return revokeRefreshTokens(tx, "id_user = ?", user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.access.forget(user.ID)
+	return &ResetPasswordRes{Reset: true}, nil
+}
+
+// findAccount looks an account up by phone number, or by email when no number is given.
+// An email shared by several accounts matches none of them.
+func (s *service) findAccount(ctx context.Context, noTelp, email string) (*User, error) {
+	var users []User
+	query := s.db.WithContext(ctx).Limit(2)
+	if noTelp != "" {
+		query = query.Where("notelp = ?", noTelp)
+	} else {
+		query = query.Where("email = ?", email)
+	}
+
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != 1 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &users[0], nil
+}
@@ -0,0 +1,291 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Access is what a user may currently do. It's read from the database rather than
+// the token, so a demotion or suspension doesn't wait for the token to expire.
+type Access struct {
+	IsAdmin     bool
+	Suspended   bool
+	Permissions []string
+	// checkout and other money moving actions need a verified phone number
+	PhoneVerified bool
+	// access tokens issued before this are no longer accepted
+	TokensValidAfter *time.Time
+}
+
+// accessCache keeps the Access of a user for a short while, the middlewares look it up
+// on every authenticated request. Changes made here invalidate the entry right away,
+// other instances pick them up after ttl.
+type accessCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[uint]cachedAccess
+}
+
+type cachedAccess struct {
+	access Access
+	until  time.Time
+}
+
+func newAccessCache(ttl time.Duration) *accessCache {
+	return &accessCache{ttl: ttl, entries: map[uint]cachedAccess{}}
+}
+
+func (c *accessCache) get(userID uint) (Access, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || time.Now().After(entry.until) {
+		delete(c.entries, userID)
+		return Access{}, false
+	}
+	return entry.access, true
+}
+
+func (c *accessCache) set(userID uint, access Access) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[userID] = cachedAccess{access: access, until: time.Now().Add(c.ttl)}
+}
+
+func (c *accessCache) forget(userID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, userID)
+}
+
+func loadPermissions(db *gorm.DB, userID uint) ([]string, error) {
+	var permissions []string
+	err := db.Table("user_role").
+		Joins("JOIN role_permission ON role_permission.id_role = user_role.id_role").
+		Joins("JOIN permission ON permission.id = role_permission.id_permission").
+		Where("user_role.id_user = ?", userID).
+		Distinct().
+		Order("permission.kode").
+		Pluck("permission.kode", &permissions).Error
+	return permissions, err
+}
+
+// assignRoles gives userID the roles, the ones it holds already are kept.
+func assignRoles(tx *gorm.DB, userID uint, roles ...string) error {
+	var ids []uint
+	if err := tx.Model(&Role{}).Where("kode IN ?", roles).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	var rows []UserRole
+	for _, id := range ids {
+		rows = append(rows, UserRole{IdUser: userID, IdRole: id, CreatedAtDate: time.Now()})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func (s *service) Access(ctx context.Context, userID uint) (*Access, error) {
+	if access, ok := s.access.get(userID); ok {
+		return &access, nil
+	}
+
+	var user User
+	if err := s.db.WithContext(ctx).Select("id", "isAdmin", "suspended_at", "tokens_valid_after", "notelp_verified_at").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusUnauthorized, ErrUserNotFound)
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	permissions, err := loadPermissions(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	access := Access{
+		IsAdmin:          user.IsAdmin,
+		Suspended:        user.SuspendedAt != nil,
+		Permissions:      permissions,
+		PhoneVerified:    user.NotelpVerifiedAt != nil,
+		TokensValidAfter: user.TokensValidAfter,
+	}
+	s.access.set(userID, access)
+	return &access, nil
+}
+
+func (s *service) HasPermissions(ctx context.Context, userID uint, permissions ...string) (missing string, err error) {
+	access, err := s.Access(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(access.Permissions, permission) {
+			return permission, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *service) GetRoles(ctx context.Context) ([]RoleRes, error) {
+	var roles []Role
+	if err := s.db.WithContext(ctx).Preload("Permissions").Order("id ASC").Find(&roles).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res := make([]RoleRes, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]string, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			permissions = append(permissions, p.Kode)
+		}
+		sort.Strings(permissions)
+		res = append(res, RoleRes{Kode: role.Kode, Nama: role.Nama, Permissions: permissions})
+	}
+	return res, nil
+}
+
+func (s *service) GetUserRoles(ctx context.Context, userID string) (*UserRolesRes, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRolesRes(ctx, user.ID)
+}
+
+func (s *service) AssignRole(ctx context.Context, userID string, input AssignRoleReq) (*UserRolesRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	actorID := uint(token.Claims.ID)
+
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role Role
+		if err := tx.First(&role, "kode = ?", input.Role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, fmt.Sprintf(ErrRoleNotFound, input.Role))
+			}
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserRole{
+			IdUser:        user.ID,
+			IdRole:        role.ID,
+			IdPemberi:     &actorID,
+			CreatedAtDate: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		// isAdmin stays in sync, ownership checks and tokens still read it
+		if role.Kode == ROLE_ADMIN {
+			return tx.Model(&User{}).Where("id = ?", user.ID).Update("isAdmin", true).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	s.access.forget(user.ID)
+	return s.userRolesRes(ctx, user.ID)
+}
+
+func (s *service) RemoveRole(ctx context.Context, userID string, roleKode string) (*UserRolesRes, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var role Role
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&role, "kode = ?", roleKode).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusNotFound, fmt.Sprintf(ErrRoleNotFound, roleKode))
+			}
+			return err
+		}
+
+		if role.Kode == ROLE_ADMIN {
+			// the role row lock above serialises concurrent removals of admins
+			var admins int64
+			if err := tx.Model(&UserRole{}).Where("id_role = ? AND id_user <> ?", role.ID, user.ID).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins == 0 {
+				return apierror.NewWarn(http.StatusConflict, ErrLastAdmin)
+			}
+		}
+
+		if err := tx.Where("id_user = ? AND id_role = ?", user.ID, role.ID).Delete(&UserRole{}).Error; err != nil {
+			return err
+		}
+
+		if role.Kode == ROLE_ADMIN {
+			return tx.Model(&User{}).Where("id = ?", user.ID).Update("isAdmin", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	s.access.forget(user.ID)
+	return s.userRolesRes(ctx, user.ID)
+}
+
+func (s *service) findUser(ctx context.Context, userID string) (*User, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, "id must be a number")
+	}
+
+	var user User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusNotFound, ErrUserNotFound)
+		}
+		return nil, apierror.FromErr(err)
+	}
+	return &user, nil
+}
+
+func (s *service) userRolesRes(ctx context.Context, userID uint) (*UserRolesRes, error) {
+	roles, err := loadUserRoles(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	permissions, err := loadPermissions(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &UserRolesRes{
+		IdUser:      int(userID),
+		Roles:       append([]string{}, roles[userID]...),
+		Permissions: append([]string{}, permissions...),
+	}, nil
+}
@@ -7,13 +7,48 @@ import (
 type LoginReq struct {
 	Notelp    string `json:"no_telp" validate:"required"`
 	KataSandi string `json:"kata_sandi" validate:"required"`
+	// client, set by the handler
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LogoutReq struct {
-	Token   string
+	Jti     string
+	IdUser  uint
+	Family  string
 	Expires time.Time
 }
 
+type LoginTwoFactorReq struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// a code of the authenticator app or an unused recovery code
+	Kode string `json:"kode" validate:"required"`
+	// client, set by the handler
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+	// client, set by the handler
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// ForgotPasswordReq names the account by its phone number or email, the code goes
+// to whichever was given.
+type ForgotPasswordReq struct {
+	NoTelp string `json:"no_telp" validate:"required_without=Email"`
+	Email  string `json:"email" validate:"required_without=NoTelp,omitempty,email"`
+}
+
+type ResetPasswordReq struct {
+	NoTelp        string `json:"no_telp" validate:"required_without=Email"`
+	Email         string `json:"email" validate:"required_without=NoTelp,omitempty,email"`
+	Kode          string `json:"kode" validate:"required,len=6,numeric"`
+	KataSandiBaru string `json:"kata_sandi_baru" validate:"required"`
+}
+
 type RegisterReq struct {
 	Nama         string  `json:"nama" validate:"required"`
 	KataSandi    string  `json:"kata_sandi" validate:"required"`
@@ -22,10 +57,9 @@ type RegisterReq struct {
 	JenisKelamin *string `json:"jenis_kelamin"`
 	Tentang      *string `json:"tentang"`
 	Pekerjaan    *string `json:"pekerjaan"`
-	Email        string  `json:"email" validate:"required"`
+	Email        string  `json:"email" validate:"required,email"`
 	IdProvinsi   *string `json:"id_provinsi"`
 	IdKota       *string `json:"id_kota"`
-	IsAdmin      *bool   `json:"isAdmin,omitempty"`
 }
 
 type UpdateProfileReq struct {
@@ -36,8 +70,39 @@ type UpdateProfileReq struct {
 	JenisKelamin *string `json:"jenis_kelamin"`
 	Tentang      *string `json:"tentang"`
 	Pekerjaan    *string `json:"pekerjaan"`
-	Email        *string `json:"email"`
+	Email        *string `json:"email" validate:"omitempty,email"`
 	IdProvinsi   *string `json:"id_provinsi"`
 	IdKota       *string `json:"id_kota"`
-	IsAdmin      *bool   `json:"isAdmin,omitempty"`
+}
+
+type VerifyContactReq struct {
+	Kode string `json:"kode" validate:"required,len=6,numeric"`
+}
+
+type TwoFactorCodeReq struct {
+	Kode string `json:"kode" validate:"required,len=6,numeric"`
+}
+
+type DisableTwoFactorReq struct {
+	KataSandi string `json:"kata_sandi" validate:"required"`
+	// a code of the authenticator app or an unused recovery code
+	Kode string `json:"kode" validate:"required"`
+}
+
+type AssignRoleReq struct {
+	Role string `json:"role" validate:"required"`
+}
+
+type ListUsersReq struct {
+	Role   string `query:"role"`
+	Status string `query:"status" validate:"omitempty,oneof=active suspended"`
+}
+
+type SuspendUserReq struct {
+	Alasan string `json:"alasan" validate:"required,max=255"`
+}
+
+type LoginAuditReq struct {
+	Hasil string `query:"hasil" validate:"omitempty,oneof=failed locked lockout unlocked"`
+	Ip    string `query:"ip"`
 }
@@ -4,9 +4,16 @@ import (
 	"time"
 )
 
+// LoginRes carries the tokens, or only the challenge when the account has 2FA and
+// the code still has to be sent to /auth/login/2fa.
 type LoginRes struct {
-	Token   string    `json:"token"`
-	Expires time.Time `json:"expires"`
+	Token             string     `json:"token,omitempty"`
+	Expires           *time.Time `json:"expires,omitempty"`
+	RefreshToken      string     `json:"refresh_token,omitempty"`
+	RefreshExpires    *time.Time `json:"refresh_expires,omitempty"`
+	TwoFactorRequired bool       `json:"two_factor_required,omitempty"`
+	ChallengeToken    string     `json:"challenge_token,omitempty"`
+	ChallengeExpires  *time.Time `json:"challenge_expires,omitempty"`
 }
 
 type VerifyTokenRes struct {
@@ -16,3 +23,102 @@ type VerifyTokenRes struct {
 type LogoutRes struct {
 	LoggedOut bool `json:"loggedOut"`
 }
+
+// OTPSentRes tells where a code went. For a password reset it's the same whether
+// the account exists or not.
+type OTPSentRes struct {
+	Kanal    string `json:"kanal"`
+	ExpireIn int    `json:"expire_in"`
+}
+
+type VerifyContactRes struct {
+	Kontak     string    `json:"kontak"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+type ResetPasswordRes struct {
+	Reset bool `json:"reset"`
+}
+
+type TwoFactorRes struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+type TwoFactorEnrollRes struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesRes is the only time the recovery codes are shown.
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RoleRes struct {
+	Kode        string   `json:"kode"`
+	Nama        string   `json:"nama"`
+	Permissions []string `json:"permissions"`
+}
+
+type UserRolesRes struct {
+	IdUser      int      `json:"id_user"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+type UserRes struct {
+	ID            int        `json:"id"`
+	Nama          string     `json:"nama"`
+	Notelp        string     `json:"notelp"`
+	Email         string     `json:"email"`
+	IsAdmin       bool       `json:"isAdmin"`
+	Roles         []string   `json:"roles"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	AlasanSuspend string     `json:"alasan_suspend,omitempty"`
+	CreatedAtDate time.Time  `json:"created_at_date"`
+}
+
+type PaginatedUserRes struct {
+	Page  int       `json:"page"`
+	Limit int       `json:"limit"`
+	Total int       `json:"total"`
+	Data  []UserRes `json:"data"`
+}
+
+type LoginAuditRes struct {
+	ID            int       `json:"id"`
+	IdUser        *uint     `json:"id_user"`
+	Notelp        string    `json:"notelp"`
+	Ip            string    `json:"ip"`
+	Hasil         string    `json:"hasil"`
+	Keterangan    string    `json:"keterangan,omitempty"`
+	IdPelaku      *uint     `json:"id_pelaku,omitempty"`
+	CreatedAtDate time.Time `json:"created_at_date"`
+}
+
+type PaginatedLoginAuditRes struct {
+	Page  int             `json:"page"`
+	Limit int             `json:"limit"`
+	Total int             `json:"total"`
+	Data  []LoginAuditRes `json:"data"`
+}
+
+type UnlockRes struct {
+	Kunci    string `json:"kunci"`
+	Unlocked bool   `json:"unlocked"`
+}
+
+type SessionRes struct {
+	ID            int        `json:"id"`
+	UserAgent     string     `json:"user_agent"`
+	Ip            string     `json:"ip"`
+	CreatedAtDate time.Time  `json:"created_at_date"`
+	LastSeenAt    *time.Time `json:"last_seen_at"`
+	Current       bool       `json:"current"`
+}
+
+type RevokeSessionsRes struct {
+	Revoked int `json:"revoked"`
+}
@@ -0,0 +1,179 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// revocations answers "is this jti revoked" and "is this session revoked" from memory
+// where it can. A revoked jti stays revoked until the token expires, a revoked session
+// until the last access token it could have issued expired, so both are cached for that
+// long. What was not revoked is only trusted for ttl, another instance may revoke it
+// meanwhile.
+type revocations struct {
+	db       *gorm.DB
+	ttl      time.Duration
+	tokenTTL time.Duration
+
+	mu              sync.Mutex
+	revoked         map[string]time.Time
+	valid           map[string]time.Time
+	revokedFamilies map[string]time.Time
+	validFamilies   map[string]time.Time
+}
+
+func newRevocations(db *gorm.DB, ttl, tokenTTL time.Duration) *revocations {
+	return &revocations{
+		db:              db,
+		ttl:             ttl,
+		tokenTTL:        tokenTTL,
+		revoked:         map[string]time.Time{},
+		valid:           map[string]time.Time{},
+		revokedFamilies: map[string]time.Time{},
+		validFamilies:   map[string]time.Time{},
+	}
+}
+
+func (r *revocations) isRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	if _, ok := r.revoked[jti]; ok {
+		r.mu.Unlock()
+		return true, nil
+	}
+	if until, ok := r.valid[jti]; ok && now.Before(until) {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.mu.Unlock()
+
+	var invalidToken InvalidToken
+	err := r.db.WithContext(ctx).Where("jti = ?", jti).First(&invalidToken).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.revoked[jti] = invalidToken.Expires
+		delete(r.valid, jti)
+		return true, nil
+	}
+	r.valid[jti] = now.Add(r.ttl)
+	return false, nil
+}
+
+func (r *revocations) revoke(ctx context.Context, token InvalidToken) error {
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&token).Error; err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.revoked[token.Jti] = token.Expires
+	delete(r.valid, token.Jti)
+	r.mu.Unlock()
+	return nil
+}
+
+// isFamilyRevoked tells whether the session of a refresh token family was ended, which
+// ends every access token issued to it. Logins from before sessions existed have no
+// row and only their jti can be revoked.
+func (r *revocations) isFamilyRevoked(ctx context.Context, family string) (bool, error) {
+	now := time.Now()
+
+	r.mu.Lock()
+	if _, ok := r.revokedFamilies[family]; ok {
+		r.mu.Unlock()
+		return true, nil
+	}
+	if until, ok := r.validFamilies[family]; ok && now.Before(until) {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.mu.Unlock()
+
+	var sessions []Session
+	if err := r.db.WithContext(ctx).Select("revoked_at").Where("family = ?", family).Limit(1).Find(&sessions).Error; err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(sessions) > 0 && sessions[0].RevokedAt != nil {
+		r.revokedFamilies[family] = sessions[0].RevokedAt.Add(r.tokenTTL)
+		delete(r.validFamilies, family)
+		return true, nil
+	}
+	r.validFamilies[family] = now.Add(r.ttl)
+	return false, nil
+}
+
+// revokeFamilies caches families whose sessions were just revoked in the database.
+func (r *revocations) revokeFamilies(families ...string) {
+	until := time.Now().Add(r.tokenTTL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, family := range families {
+		r.revokedFamilies[family] = until
+		delete(r.validFamilies, family)
+	}
+}
+
+// purge drops rows and cache entries of tokens that expired, they fail validation
+// on their own from then on.
+func (r *revocations) purge(ctx context.Context) error {
+	now := time.Now()
+
+	if err := r.db.WithContext(ctx).Where("expires < ?", now).Delete(&InvalidToken{}).Error; err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for jti, expires := range r.revoked {
+		if expires.Before(now) {
+			delete(r.revoked, jti)
+		}
+	}
+	for jti, until := range r.valid {
+		if until.Before(now) {
+			delete(r.valid, jti)
+		}
+	}
+	for family, until := range r.revokedFamilies {
+		if until.Before(now) {
+			delete(r.revokedFamilies, family)
+		}
+	}
+	for family, until := range r.validFamilies {
+		if until.Before(now) {
+			delete(r.validFamilies, family)
+		}
+	}
+	return nil
+}
+
+func (r *revocations) runPurge(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := r.purge(context.Background()); err != nil {
+			logger.Error(context.Background(), "failed to purge revoked tokens: %v", err)
+		}
+	}
+}
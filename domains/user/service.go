@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -11,78 +12,196 @@ import (
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/devanadindraa/Evermos-Backend/utils/keyset"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Service interface {
 	Login(ctx context.Context, input LoginReq) (res *LoginRes, err error)
+	LoginTwoFactor(ctx context.Context, input LoginTwoFactorReq) (res *LoginRes, err error)
+	Refresh(ctx context.Context, input RefreshReq) (res *LoginRes, err error)
 	Logout(ctx context.Context, input LogoutReq) (res *LogoutRes, err error)
-	ValidateToken(ctx context.Context, token string) (err error)
+	ForgotPassword(ctx context.Context, input ForgotPasswordReq) (res *OTPSentRes, err error)
+	ResetPassword(ctx context.Context, input ResetPasswordReq) (res *ResetPasswordRes, err error)
+	SendVerification(ctx context.Context, contact string) (*OTPSentRes, error)
+	VerifyContact(ctx context.Context, contact string, input VerifyContactReq) (*VerifyContactRes, error)
+	GetTwoFactor(ctx context.Context) (*TwoFactorRes, error)
+	EnrollTwoFactor(ctx context.Context) (*TwoFactorEnrollRes, error)
+	EnableTwoFactor(ctx context.Context, input TwoFactorCodeReq) (*RecoveryCodesRes, error)
+	DisableTwoFactor(ctx context.Context, input DisableTwoFactorReq) (*TwoFactorRes, error)
+	RegenerateRecoveryCodes(ctx context.Context, input TwoFactorCodeReq) (*RecoveryCodesRes, error)
+	ValidateToken(ctx context.Context, claims constants.JWTClaims) (err error)
+	// TouchSession records that the session of family was used, without a database write
+	TouchSession(family string)
+	GetSessions(ctx context.Context) ([]SessionRes, error)
+	RevokeSession(ctx context.Context, sessionID string) (*RevokeSessionsRes, error)
+	RevokeAllSessions(ctx context.Context) (*RevokeSessionsRes, error)
 	Register(ctx context.Context, input RegisterReq) (res *User, err error)
 	UpdateProfile(ctx context.Context, input UpdateProfileReq) (res *User, err error)
 	GetProfile(ctx context.Context) (*User, error)
+	JWKS() keyset.JWKSet
+	// Access returns the current admin flag, suspension and permissions of a user
+	Access(ctx context.Context, userID uint) (*Access, error)
+	// HasPermissions returns the first of permissions the user lacks, "" when it has them all
+	HasPermissions(ctx context.Context, userID uint, permissions ...string) (missing string, err error)
+	GetRoles(ctx context.Context) ([]RoleRes, error)
+	GetUserRoles(ctx context.Context, userID string) (*UserRolesRes, error)
+	AssignRole(ctx context.Context, userID string, input AssignRoleReq) (*UserRolesRes, error)
+	RemoveRole(ctx context.Context, userID string, role string) (*UserRolesRes, error)
+	UnlockUser(ctx context.Context, userID string) (*UnlockRes, error)
+	UnlockIP(ctx context.Context, ip string) (*UnlockRes, error)
+	ListLoginAudit(ctx context.Context, filter *constants.FilterReq, input LoginAuditReq) (*PaginatedLoginAuditRes, error)
+	ListUsers(ctx context.Context, filter *constants.FilterReq, input ListUsersReq) (*PaginatedUserRes, error)
+	GetUser(ctx context.Context, userID string) (*UserRes, error)
+	PromoteUser(ctx context.Context, userID string) (*UserRes, error)
+	DemoteUser(ctx context.Context, userID string) (*UserRes, error)
+	SuspendUser(ctx context.Context, userID string, input SuspendUserReq) (*UserRes, error)
+	UnsuspendUser(ctx context.Context, userID string) (*UserRes, error)
 }
 
 type service struct {
-	authConfig config.Auth
-	db         *gorm.DB
+	authConfig  config.Auth
+	db          *gorm.DB
+	keys        *keyset.KeySet
+	revocations *revocations
+	access      *accessCache
+	notifier    notifier.Notifier
+	lastSeen    *lastSeen
 }
 
-func NewService(config *config.Config, db *gorm.DB) Service {
+func NewService(config *config.Config, db *gorm.DB, keys *keyset.KeySet, notifier notifier.Notifier) Service {
+	revocations := newRevocations(db, config.Auth.JWT.RevocationCacheTTL, config.Auth.JWT.ExpireIn)
+	go revocations.runPurge(config.Auth.JWT.RevocationPurgeInterval)
+
+	lastSeen := newLastSeen(db)
+	go lastSeen.run(config.Auth.JWT.LastSeenFlushInterval)
+
 	return &service{
-		authConfig: config.Auth,
-		db:         db,
+		authConfig:  config.Auth,
+		db:          db,
+		keys:        keys,
+		revocations: revocations,
+		access:      newAccessCache(config.Auth.JWT.PermissionCacheTTL),
+		notifier:    notifier,
+		lastSeen:    lastSeen,
 	}
 }
 
 func (s *service) Login(ctx context.Context, input LoginReq) (*LoginRes, error) {
+	if err := s.beginLogin(ctx, input.Notelp, input.IP); err != nil {
+		return nil, err
+	}
 
-	var err error
 	var user User
-	if err = s.db.WithContext(ctx).Where("notelp = ?", input.Notelp).First(&user).Error; err == nil {
-		if !comparePassword(user.KataSandi, input.KataSandi) {
+	if err := s.db.WithContext(ctx).Where("notelp = ?", input.Notelp).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// unknown numbers are throttled like accounts, the answer doesn't tell them apart
+			s.finishLogin(ctx, input.Notelp, input.IP, nil, false, "unknown account")
 			return nil, apierror.NewWarn(http.StatusUnauthorized, ErrInvalidCredentials)
 		}
+		return nil, apierror.FromErr(err)
 	}
 
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, apierror.NewWarn(http.StatusUnauthorized, ErrInvalidCredentials)
-		}
-		return nil, err
+	if !comparePassword(user.KataSandi, input.KataSandi) {
+		s.finishLogin(ctx, input.Notelp, input.IP, &user.ID, false, "wrong password")
+		return nil, apierror.NewWarn(http.StatusUnauthorized, ErrInvalidCredentials)
 	}
+	s.finishLogin(ctx, input.Notelp, input.IP, &user.ID, true, "")
 
-	expirationTime := time.Now().Add(s.authConfig.JWT.ExpireIn)
-	claims := &constants.JWTClaims{
-		ID:      int(user.ID),
-		IsAdmin: bool(user.IsAdmin),
-		NoTelp:  input.Notelp,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+	if user.SuspendedAt != nil {
+		return nil, apierror.NewWarn(http.StatusForbidden, ErrAccountSuspended)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.authConfig.JWT.SecretKey))
+	enabled, err := s.twoFactorEnabled(s.db.WithContext(ctx), user.ID)
 	if err != nil {
 		return nil, apierror.FromErr(err)
 	}
+	if enabled {
+		return s.newLoginChallenge(ctx, user)
+	}
 
-	return &LoginRes{
-		Token:   tokenString,
-		Expires: expirationTime,
-	}, nil
+	res, err := s.issueTokens(s.db.WithContext(ctx), user, "", false, clientInfo{IP: input.IP, UserAgent: input.UserAgent})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return res, nil
 }
 
-func (s *service) Logout(ctx context.Context, input LogoutReq) (res *LogoutRes, err error) {
+// Refresh rotates a refresh token: the presented one is marked used and a new pair is
+// issued in the same family. A token that was used before means it leaked, so the
+// whole family is revoked and its holder has to log in again.
+func (s *service) Refresh(ctx context.Context, input RefreshReq) (res *LoginRes, err error) {
+	reusedFamily := ""
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var refresh RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&refresh, "token_hash = ?", hashRefreshToken(input.RefreshToken)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusUnauthorized, ErrInvalidRefresh)
+			}
+			return err
+		}
+
+		if refresh.RevokedAt != nil || !refresh.Expires.After(time.Now()) {
+			return apierror.NewWarn(http.StatusUnauthorized, ErrInvalidRefresh)
+		}
+
+		if refresh.UsedAt != nil {
+			// committed on purpose, the revocation has to survive the rejected request
+			reusedFamily = refresh.Family
+			return revokeRefreshTokens(tx, "family = ?", refresh.Family)
+		}
 
-	invalidToken := InvalidToken(input)
+		now := time.Now()
+		if err := tx.Model(&refresh).Update("used_at", now).Error; err != nil {
+			return err
+		}
 
-	err = s.db.WithContext(ctx).Create(&invalidToken).Error
+		var user User
+		if err := tx.First(&user, "id = ?", refresh.IdUser).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusUnauthorized, ErrInvalidRefresh)
+			}
+			return err
+		}
+		if user.SuspendedAt != nil {
+			return apierror.NewWarn(http.StatusForbidden, ErrAccountSuspended)
+		}
 
+		res, err = s.issueTokens(tx, user, refresh.Family, refresh.Mfa, clientInfo{IP: input.IP, UserAgent: input.UserAgent})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, apierror.FromErr(err)
+	}
+	if reusedFamily != "" {
+		// access tokens of the family may be in the hands of whoever reused it
+		s.revocations.revokeFamilies(reusedFamily)
+		return nil, apierror.NewWarn(http.StatusUnauthorized, ErrRefreshReused)
+	}
+
+	return res, nil
+}
+
+func (s *service) Logout(ctx context.Context, input LogoutReq) (res *LogoutRes, err error) {
+	userID := input.IdUser
+	if err := s.revocations.revoke(ctx, InvalidToken{
+		Jti:     input.Jti,
+		IdUser:  &userID,
+		Expires: input.Expires,
+	}); err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	if input.Family != "" {
+		if err := revokeRefreshTokens(s.db.WithContext(ctx), "family = ?", input.Family); err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		s.revocations.revokeFamilies(input.Family)
 	}
 
 	return &LogoutRes{
@@ -90,14 +209,29 @@ func (s *service) Logout(ctx context.Context, input LogoutReq) (res *LogoutRes,
 	}, nil
 }
 
-func (s *service) ValidateToken(ctx context.Context, token string) (err error) {
+// ValidateToken rejects tokens that were revoked, by jti or by ending the session they
+// were issued to. Tokens without a jti can't be revoked and are refused as well.
+func (s *service) ValidateToken(ctx context.Context, claims constants.JWTClaims) (err error) {
+	if claims.RegisteredClaims.ID == "" {
+		return apierror.NewWarn(http.StatusUnauthorized, ErrTokenWithoutJti)
+	}
 
-	var invalidToken InvalidToken
-	if err := s.db.WithContext(ctx).Where("token = ?", token).First(&invalidToken).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil
+	revoked, err := s.revocations.isRevoked(ctx, claims.RegisteredClaims.ID)
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+	if revoked {
+		return apierror.NewWarn(http.StatusUnauthorized, ErrTokenRevoked)
+	}
+
+	if claims.Family != "" {
+		revoked, err = s.revocations.isFamilyRevoked(ctx, claims.Family)
+		if err != nil {
+			return apierror.FromErr(err)
+		}
+		if revoked {
+			return apierror.NewWarn(http.StatusUnauthorized, ErrTokenRevoked)
 		}
-		return err
 	}
 
 	return nil
@@ -126,17 +260,16 @@ func (s *service) Register(ctx context.Context, input RegisterReq) (res *User, e
 		Email:         input.Email,
 		IdProvinsi:    GetStringOrDefault(input.IdProvinsi, ""),
 		IdKota:        GetStringOrDefault(input.IdKota, ""),
-		IsAdmin:       GetBoolOrDefault(input.IsAdmin, false),
 		CreatedAtDate: time.Now(),
 		UpdatedAtDate: time.Now(),
 	}
 
-	// Insert into DB
-	if err := s.db.WithContext(ctx).Create(&user).Error; err != nil {
-		return nil, apierror.FromErr(err)
-	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Insert into DB
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
 
-	if !user.IsAdmin {
 		toko := shop.Toko{
 			IdUser:        user.ID,
 			NamaToko:      fmt.Sprintf("Toko %s", user.Nama),
@@ -144,9 +277,14 @@ func (s *service) Register(ctx context.Context, input RegisterReq) (res *User, e
 			UpdatedAtDate: time.Now(),
 		}
 
-		if err := s.db.WithContext(ctx).Create(&toko).Error; err != nil {
-			return nil, apierror.FromErr(fmt.Errorf("gagal membuat toko: %w", err))
+		if err := tx.Create(&toko).Error; err != nil {
+			return fmt.Errorf("gagal membuat toko: %w", err)
 		}
+
+		return assignRoles(tx, user.ID, ROLE_SELLER, ROLE_BUYER)
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
 	}
 
 	return &user, nil
@@ -167,13 +305,16 @@ func (s *service) UpdateProfile(ctx context.Context, input UpdateProfileReq) (re
 	if input.Nama != nil {
 		user.Nama = *input.Nama
 	}
+	passwordChanged := false
 	if input.KataSandi != nil {
 		hashedPassword, err := hashPassword(*input.KataSandi)
 		if err != nil {
 			return nil, apierror.FromErr(err)
 		}
 		user.KataSandi = hashedPassword
+		passwordChanged = true
 	}
+	phoneChanged := input.NoTelp != nil && *input.NoTelp != user.Notelp
 	if input.NoTelp != nil {
 		user.Notelp = *input.NoTelp
 	}
@@ -187,6 +328,7 @@ func (s *service) UpdateProfile(ctx context.Context, input UpdateProfileReq) (re
 	if input.Pekerjaan != nil {
 		user.Pekerjaan = *input.Pekerjaan
 	}
+	emailChanged := input.Email != nil && *input.Email != user.Email
 	if input.Email != nil {
 		user.Email = *input.Email
 	}
@@ -196,16 +338,51 @@ func (s *service) UpdateProfile(ctx context.Context, input UpdateProfileReq) (re
 	if input.IdKota != nil {
 		user.IdKota = *input.IdKota
 	}
-	if input.IsAdmin != nil {
-		user.IsAdmin = *input.IsAdmin
-	}
 
 	user.UpdatedAtDate = time.Now()
 
-	if err := s.db.WithContext(ctx).Save(&user).Error; err != nil {
+	var families []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// admin, suspension and verification are only changed by their own flows, a
+		// concurrent demotion must not be undone by this save
+		if err := tx.Omit("isAdmin", "suspended_at", "alasan_suspend", "tokens_valid_after",
+			"notelp_verified_at", "email_verified_at").Save(&user).Error; err != nil {
+			return err
+		}
+		// a new phone number or email has to be verified again
+		if phoneChanged {
+			if err := unverifyContact(tx, user.ID, CONTACT_PHONE); err != nil {
+				return err
+			}
+			user.NotelpVerifiedAt = nil
+		}
+		if emailChanged {
+			if err := unverifyContact(tx, user.ID, CONTACT_EMAIL); err != nil {
+				return err
+			}
+			user.EmailVerifiedAt = nil
+		}
+		// a new password ends every session like a reset does, every device has to
+		// log in again
+		if passwordChanged {
+			if err := tx.Model(&User{}).Where("id = ?", user.ID).Update("tokens_valid_after", user.UpdatedAtDate).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Session{}).Where("id_user = ? AND revoked_at IS NULL", user.ID).Pluck("family", &families).Error; err != nil {
+				return err
+			}
+			return revokeRefreshTokens(tx, "id_user = ?", user.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, apierror.FromErr(err)
 	}
 
+	if phoneChanged || passwordChanged {
+		s.access.forget(user.ID)
+	}
+	s.revocations.revokeFamilies(families...)
 	return &user, nil
 }
 
@@ -226,3 +403,8 @@ func (s *service) GetProfile(ctx context.Context) (*User, error) {
 
 	return &user, nil
 }
+
+// JWKS publishes the public keys tokens can currently be verified with.
+func (s *service) JWKS() keyset.JWKSet {
+	return s.keys.JWKS()
+}
@@ -0,0 +1,241 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+	"gorm.io/gorm"
+)
+
+const MAX_USER_AGENT = 512
+
+// clientInfo describes where a login or refresh came from.
+type clientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// lastSeen buffers when sessions were used. Requests only mark the session in memory,
+// run writes the marks every interval so a busy client costs one UPDATE per interval.
+type lastSeen struct {
+	db *gorm.DB
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newLastSeen(db *gorm.DB) *lastSeen {
+	return &lastSeen{db: db, seen: map[string]time.Time{}}
+}
+
+func (l *lastSeen) touch(family string, at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seen[family] = at
+}
+
+// get returns the unwritten mark of family, if any.
+func (l *lastSeen) get(family string) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	at, ok := l.seen[family]
+	return at, ok
+}
+
+func (l *lastSeen) flush(ctx context.Context) error {
+	l.mu.Lock()
+	pending := l.seen
+	l.seen = map[string]time.Time{}
+	l.mu.Unlock()
+
+	for family, at := range pending {
+		if err := l.db.WithContext(ctx).Model(&Session{}).
+			Where("family = ? AND (last_seen_at IS NULL OR last_seen_at < ?)", family, at).
+			UpdateColumn("last_seen_at", at).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *lastSeen) run(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := l.flush(context.Background()); err != nil {
+			logger.Error(context.Background(), "failed to write session last seen: %v", err)
+		}
+	}
+}
+
+// TouchSession marks the session of family as used now, it's written in the background.
+func (s *service) TouchSession(family string) {
+	if family == "" {
+		return
+	}
+	s.lastSeen.touch(family, time.Now())
+}
+
+// saveSession records a new login or, for an existing family, the tokens a refresh
+// issued. Logins from before sessions existed have no row and are left alone.
+func saveSession(tx *gorm.DB, userID uint, family string, newFamily bool, jti string, accessExpires, expires time.Time, client clientInfo) error {
+	now := time.Now()
+	userAgent := client.UserAgent
+	if len(userAgent) > MAX_USER_AGENT {
+		userAgent = userAgent[:MAX_USER_AGENT]
+	}
+
+	if newFamily {
+		return tx.Create(&Session{
+			IdUser:        userID,
+			Family:        family,
+			Jti:           jti,
+			AccessExpires: &accessExpires,
+			UserAgent:     userAgent,
+			Ip:            client.IP,
+			Expires:       expires,
+			LastSeenAt:    &now,
+			CreatedAtDate: now,
+			UpdatedAtDate: now,
+		}).Error
+	}
+
+	updates := map[string]any{
+		"jti":            jti,
+		"access_expires": accessExpires,
+		"expires":        expires,
+		"last_seen_at":   now,
+	}
+	if client.IP != "" {
+		updates["ip"] = client.IP
+	}
+	if userAgent != "" {
+		updates["user_agent"] = userAgent
+	}
+	return tx.Model(&Session{}).Where("family = ?", family).Updates(updates).Error
+}
+
+func (s *service) GetSessions(ctx context.Context) ([]SessionRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var sessions []Session
+	if err := s.db.WithContext(ctx).
+		Where("id_user = ? AND revoked_at IS NULL AND expires > ?", token.Claims.ID, time.Now()).
+		Order("created_at_date DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	res := make([]SessionRes, 0, len(sessions))
+	for _, session := range sessions {
+		lastSeenAt := session.LastSeenAt
+		if at, ok := s.lastSeen.get(session.Family); ok && (lastSeenAt == nil || at.After(*lastSeenAt)) {
+			lastSeenAt = &at
+		}
+
+		res = append(res, SessionRes{
+			ID:            int(session.ID),
+			UserAgent:     session.UserAgent,
+			Ip:            session.Ip,
+			CreatedAtDate: session.CreatedAtDate,
+			LastSeenAt:    lastSeenAt,
+			Current:       session.Family == token.Claims.Family,
+		})
+	}
+	return res, nil
+}
+
+// RevokeSession logs one of the caller's sessions out: its refresh tokens are revoked
+// and so is the access token it got last.
+func (s *service) RevokeSession(ctx context.Context, sessionID string) (*RevokeSessionsRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	id, err := strconv.Atoi(sessionID)
+	if err != nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, "id must be a number")
+	}
+
+	var session Session
+	if err := s.db.WithContext(ctx).
+		First(&session, "id = ? AND id_user = ? AND revoked_at IS NULL", id, token.Claims.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusNotFound, ErrSessionNotFound)
+		}
+		return nil, apierror.FromErr(err)
+	}
+
+	if err := s.endSessions(ctx, []Session{session}, "family = ?", session.Family); err != nil {
+		return nil, err
+	}
+	return &RevokeSessionsRes{Revoked: 1}, nil
+}
+
+// RevokeAllSessions logs the caller out everywhere, the current session included.
+func (s *service) RevokeAllSessions(ctx context.Context) (*RevokeSessionsRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var sessions []Session
+	if err := s.db.WithContext(ctx).
+		Where("id_user = ? AND revoked_at IS NULL", token.Claims.ID).
+		Find(&sessions).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	if err := s.endSessions(ctx, sessions, "id_user = ?", token.Claims.ID); err != nil {
+		return nil, err
+	}
+	return &RevokeSessionsRes{Revoked: len(sessions)}, nil
+}
+
+// endSessions revokes the refresh tokens matching query, which marks the sessions revoked
+// and so refuses every access token issued to them. The last access token of each is
+// blacklisted by jti as well, for instances that still trust the session from cache.
+func (s *service) endSessions(ctx context.Context, sessions []Session, query string, args ...any) error {
+	now := time.Now()
+	for _, session := range sessions {
+		if session.Jti == "" || session.AccessExpires == nil || !session.AccessExpires.After(now) {
+			continue
+		}
+
+		userID := session.IdUser
+		if err := s.revocations.revoke(ctx, InvalidToken{
+			Jti:     session.Jti,
+			IdUser:  &userID,
+			Expires: *session.AccessExpires,
+		}); err != nil {
+			return apierror.FromErr(err)
+		}
+	}
+
+	if err := revokeRefreshTokens(s.db.WithContext(ctx), query, args...); err != nil {
+		return apierror.FromErr(err)
+	}
+
+	families := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		families = append(families, session.Family)
+	}
+	s.revocations.revokeFamilies(families...)
+	return nil
+}
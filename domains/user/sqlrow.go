@@ -4,32 +4,203 @@ import (
 	"time"
 )
 
+// InvalidToken is a revoked token, identified by its jti and kept until it expires.
 type InvalidToken struct {
-	Token   string
-	Expires time.Time
+	Jti           string `gorm:"primaryKey"`
+	IdUser        *uint
+	Expires       time.Time
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
 }
 
 func (InvalidToken) TableName() string {
 	return "invalid_token"
 }
 
-type User struct {
-	ID            uint      `gorm:"primaryKey;autoIncrement"`
-	Nama          string    `json:"nama"`
-	KataSandi     string    `json:"kata_sandi"`
-	Notelp        string    `json:"notelp" gorm:"unique"`
-	TanggalLahir  time.Time `json:"tanggal_Lahir" gorm:"type:date"`
-	JenisKelamin  string    `json:"jenis_kelasmin"`
-	Tentang       string    `json:"tentang"`
-	Pekerjaan     string    `json:"pekerjaan"`
-	Email         string    `json:"email"`
-	IdProvinsi    string    `json:"id_provinsi"`
-	IdKota        string    `json:"id_kota"`
-	IsAdmin       bool      `json:"isAdmin" gorm:"column:isAdmin;default:false"`
+// RefreshToken stores the sha256 of a refresh token. Tokens of one login share a Family,
+// each refresh marks the presented token used and adds the next one to the family.
+type RefreshToken struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        uint `gorm:"not null"`
+	Family        string
+	Mfa           bool
+	TokenHash     string
+	Expires       time.Time
+	UsedAt        *time.Time
+	RevokedAt     *time.Time
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_token"
+}
+
+// OTP is a one-time code sent to a user, only its HMAC is stored. Tujuan is what the
+// code is for, a new code of the same tujuan replaces the previous one.
+type OTP struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        uint `gorm:"not null"`
+	Tujuan        string
+	Kanal         string
+	CodeHash      string
+	Expires       time.Time
+	Attempts      int
+	UsedAt        *time.Time
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+func (OTP) TableName() string {
+	return "otp"
+}
+
+// UserTOTP is the authenticator secret of a user, encrypted with a key derived from the
+// JWT secret. EnabledAt is nil while enrollment waits for the first code.
+type UserTOTP struct {
+	IdUser        uint `gorm:"primaryKey;autoIncrement:false"`
+	SecretEnc     string
+	EnabledAt     *time.Time
+	LastCounter   int64
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (UserTOTP) TableName() string {
+	return "user_totp"
+}
+
+type RecoveryCode struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        uint
+	CodeHash      string
+	UsedAt        *time.Time
 	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+type LoginChallenge struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        uint
+	TokenHash     string
+	Expires       time.Time
+	Attempts      int
+	UsedAt        *time.Time
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+func (LoginChallenge) TableName() string {
+	return "login_challenge"
+}
+
+// LoginThrottle counts failed logins of one account or IP, see config.Lockout.
+type LoginThrottle struct {
+	Kunci         string `gorm:"primaryKey"`
+	Gagal         int
+	TerakhirGagal *time.Time
+	LockedUntil   *time.Time
 	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
 }
 
+func (LoginThrottle) TableName() string {
+	return "login_throttle"
+}
+
+type LoginAudit struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        *uint
+	Notelp        string
+	Ip            string
+	Hasil         string
+	Keterangan    string
+	IdPelaku      *uint
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+func (LoginAudit) TableName() string {
+	return "login_audit"
+}
+
+// Session is a login as the user sees it, one per refresh token Family.
+type Session struct {
+	ID            uint `gorm:"primaryKey"`
+	IdUser        uint
+	Family        string
+	Jti           string
+	AccessExpires *time.Time
+	UserAgent     string
+	Ip            string
+	Expires       time.Time
+	LastSeenAt    *time.Time
+	RevokedAt     *time.Time
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Session) TableName() string {
+	return "session"
+}
+
+type User struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement"`
+	Nama             string     `json:"nama"`
+	KataSandi        string     `json:"kata_sandi"`
+	Notelp           string     `json:"notelp" gorm:"unique"`
+	NotelpVerifiedAt *time.Time `json:"notelp_verified_at"`
+	TanggalLahir     time.Time  `json:"tanggal_Lahir" gorm:"type:date"`
+	JenisKelamin     string     `json:"jenis_kelasmin"`
+	Tentang          string     `json:"tentang"`
+	Pekerjaan        string     `json:"pekerjaan"`
+	Email            string     `json:"email"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	IdProvinsi       string     `json:"id_provinsi"`
+	IdKota           string     `json:"id_kota"`
+	IsAdmin          bool       `json:"isAdmin" gorm:"column:isAdmin;default:false"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	AlasanSuspend    string     `json:"alasan_suspend,omitempty"`
+	// access tokens issued before this are refused, set when a password is reset
+	TokensValidAfter *time.Time `json:"-"`
+	CreatedAtDate    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAtDate    time.Time  `gorm:"autoUpdateTime"`
+}
+
 func (User) TableName() string {
 	return "user"
 }
+
+type Role struct {
+	ID            uint         `gorm:"primaryKey"`
+	Kode          string       `json:"kode"`
+	Nama          string       `json:"nama"`
+	Permissions   []Permission `gorm:"many2many:role_permission;joinForeignKey:IdRole;joinReferences:IdPermission"`
+	CreatedAtDate time.Time    `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (Role) TableName() string {
+	return "role"
+}
+
+type Permission struct {
+	ID            uint      `gorm:"primaryKey"`
+	Kode          string    `json:"kode"`
+	Deskripsi     string    `json:"deskripsi"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Permission) TableName() string {
+	return "permission"
+}
+
+type UserRole struct {
+	IdUser        uint `gorm:"primaryKey;autoIncrement:false"`
+	IdRole        uint `gorm:"primaryKey;autoIncrement:false"`
+	IdPemberi     *uint
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+}
+
+func (UserRole) TableName() string {
+	return "user_role"
+}
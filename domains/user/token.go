@@ -0,0 +1,106 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// issueTokens signs a short lived access token and stores a new refresh token of the
+// given family. An empty family starts a new one, as on login. mfa records that the
+// login passed a second factor, refreshes of the family keep it. The session of the
+// family is created or updated with client.
+func (s *service) issueTokens(tx *gorm.DB, user User, family string, mfa bool, client clientInfo) (*LoginRes, error) {
+	newFamily := family == ""
+	if newFamily {
+		family = uuid.NewString()
+	}
+	now := time.Now()
+
+	expirationTime := now.Add(s.authConfig.JWT.ExpireIn)
+	claims := &constants.JWTClaims{
+		ID:      int(user.ID),
+		IsAdmin: bool(user.IsAdmin),
+		NoTelp:  user.Notelp,
+		Family:  family,
+		Mfa:     mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
+	}
+
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshExpires := now.Add(s.authConfig.JWT.RefreshExpireIn)
+
+	if err := tx.Create(&RefreshToken{
+		IdUser:        user.ID,
+		Family:        family,
+		Mfa:           mfa,
+		TokenHash:     hashRefreshToken(refresh),
+		Expires:       refreshExpires,
+		CreatedAtDate: now,
+		UpdatedAtDate: now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := saveSession(tx, user.ID, family, newFamily, claims.RegisteredClaims.ID, expirationTime, refreshExpires, client); err != nil {
+		return nil, err
+	}
+
+	return &LoginRes{
+		Token:          tokenString,
+		Expires:        &expirationTime,
+		RefreshToken:   refresh,
+		RefreshExpires: &refreshExpires,
+	}, nil
+}
+
+// revokeRefreshTokens revokes every live refresh token matching the query, e.g. one
+// family or all tokens of a user, and ends the matching sessions. The query may only
+// use columns both tables have, family and id_user.
+func revokeRefreshTokens(tx *gorm.DB, query string, args ...any) error {
+	now := time.Now()
+	if err := tx.Model(&RefreshToken{}).
+		Where("revoked_at IS NULL").
+		Where(query, args...).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&Session{}).
+		Where("revoked_at IS NULL").
+		Where(query, args...).
+		Update("revoked_at", now).Error
+}
+
+func newRefreshToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// refresh tokens are random and long, a fast hash is enough to keep them useless if leaked
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
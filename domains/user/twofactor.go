@@ -0,0 +1,437 @@
+package user
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TOTP_SKEW accepts the code of the previous and the next 30s step as well
+const TOTP_SKEW = 1
+
+func (s *service) GetTwoFactor(ctx context.Context) (*TwoFactorRes, error) {
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactorRes(s.db.WithContext(ctx), user.ID)
+}
+
+// EnrollTwoFactor creates a new secret for the authenticator app. 2FA only becomes
+// active once EnableTwoFactor saw a code of it, enrolling again replaces the secret.
+func (s *service) EnrollTwoFactor(ctx context.Context) (*TwoFactorEnrollRes, error) {
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	sealed, err := s.sealSecret(secret)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current UserTOTP
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id_user = ?", user.ID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && current.EnabledAt != nil {
+			return apierror.NewWarn(http.StatusConflict, ErrTwoFactorEnabled)
+		}
+
+		return tx.Save(&UserTOTP{
+			IdUser:        user.ID,
+			SecretEnc:     sealed,
+			CreatedAtDate: time.Now(),
+			UpdatedAtDate: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &TwoFactorEnrollRes{
+		Secret:     secret,
+		OtpauthURI: totp.URI(s.authConfig.TwoFactor.Issuer, user.Notelp, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns 2FA on with a first code of the enrolled secret and hands out
+// the recovery codes.
+func (s *service) EnableTwoFactor(ctx context.Context, input TwoFactorCodeReq) (*RecoveryCodesRes, error) {
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current UserTOTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id_user = ?", user.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusBadRequest, ErrNoEnrollment)
+			}
+			return err
+		}
+		if current.EnabledAt != nil {
+			return apierror.NewWarn(http.StatusConflict, ErrTwoFactorEnabled)
+		}
+
+		ok, err := s.checkTOTP(tx, &current, input.Kode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apierror.NewWarn(http.StatusBadRequest, ErrInvalidTwoFactor)
+		}
+
+		if err := tx.Model(&current).Update("enabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &RecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor needs the password and a code, a stolen access token alone can't
+// switch 2FA off.
+func (s *service) DisableTwoFactor(ctx context.Context, input DisableTwoFactorReq) (*TwoFactorRes, error) {
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !comparePassword(user.KataSandi, input.KataSandi) {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrInvalidCurPassword)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ok, err := s.checkSecondFactor(tx, user.ID, input.Kode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apierror.NewWarn(http.StatusBadRequest, ErrInvalidTwoFactor)
+		}
+
+		if err := tx.Where("id_user = ?", user.ID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id_user = ?", user.ID).Delete(&UserTOTP{}).Error
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.twoFactorRes(s.db.WithContext(ctx), user.ID)
+}
+
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, input TwoFactorCodeReq) (*RecoveryCodesRes, error) {
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current UserTOTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id_user = ? AND enabled_at IS NOT NULL", user.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusBadRequest, ErrTwoFactorDisabled)
+			}
+			return err
+		}
+
+		ok, err := s.checkTOTP(tx, &current, input.Kode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apierror.NewWarn(http.StatusBadRequest, ErrInvalidTwoFactor)
+		}
+
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &RecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+// LoginTwoFactor finishes a login that Login answered with a challenge. Wrong codes
+// count against the challenge, after OTP.MaxAttempts the login has to start over.
+func (s *service) LoginTwoFactor(ctx context.Context, input LoginTwoFactorReq) (res *LoginRes, err error) {
+	tokenHash := hashRefreshToken(input.ChallengeToken)
+
+	// codes are throttled per account and IP like passwords, on top of the challenge attempts
+	var owner User
+	if err := s.db.WithContext(ctx).
+		Where("id = (?)", s.db.Model(&LoginChallenge{}).Select("id_user").Where("token_hash = ?", tokenHash)).
+		First(&owner).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusUnauthorized, ErrInvalidChallenge)
+		}
+		return nil, apierror.FromErr(err)
+	}
+	if err := s.beginLogin(ctx, owner.Notelp, input.IP); err != nil {
+		return nil, err
+	}
+
+	wrong := false
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var challenge LoginChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&challenge, "token_hash = ?", tokenHash).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierror.NewWarn(http.StatusUnauthorized, ErrInvalidChallenge)
+			}
+			return err
+		}
+
+		if challenge.UsedAt != nil || !challenge.Expires.After(time.Now()) ||
+			challenge.Attempts >= s.authConfig.OTP.MaxAttempts {
+			return apierror.NewWarn(http.StatusUnauthorized, ErrInvalidChallenge)
+		}
+
+		var user User
+		if err := tx.First(&user, "id = ?", challenge.IdUser).Error; err != nil {
+			return err
+		}
+		if user.SuspendedAt != nil {
+			return apierror.NewWarn(http.StatusForbidden, ErrAccountSuspended)
+		}
+
+		ok, err := s.checkSecondFactor(tx, user.ID, input.Kode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// committed on purpose, the attempt has to count even though the login fails
+			wrong = true
+			return tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error
+		}
+
+		if err := tx.Model(&challenge).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		res, err = s.issueTokens(tx, user, "", true, clientInfo{IP: input.IP, UserAgent: input.UserAgent})
+		return err
+	})
+	if err != nil {
+		s.finishLogin(ctx, owner.Notelp, input.IP, &owner.ID, false, "two-factor step rejected")
+		return nil, apierror.FromErr(err)
+	}
+	if wrong {
+		s.finishLogin(ctx, owner.Notelp, input.IP, &owner.ID, false, "wrong two-factor code")
+		return nil, apierror.NewWarn(http.StatusUnauthorized, ErrInvalidTwoFactor)
+	}
+	s.finishLogin(ctx, owner.Notelp, input.IP, &owner.ID, true, "")
+
+	return res, nil
+}
+
+// newLoginChallenge answers the password step of a login with 2FA.
+func (s *service) newLoginChallenge(ctx context.Context, user User) (*LoginRes, error) {
+	token, err := newRefreshToken()
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	expires := time.Now().Add(s.authConfig.TwoFactor.ChallengeExpireIn)
+	if err := s.db.WithContext(ctx).Create(&LoginChallenge{
+		IdUser:        user.ID,
+		TokenHash:     hashRefreshToken(token),
+		Expires:       expires,
+		CreatedAtDate: time.Now(),
+	}).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &LoginRes{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ChallengeExpires:  &expires,
+	}, nil
+}
+
+func (s *service) twoFactorEnabled(db *gorm.DB, userID uint) (bool, error) {
+	var count int64
+	err := db.Model(&UserTOTP{}).Where("id_user = ? AND enabled_at IS NOT NULL", userID).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *service) twoFactorRes(db *gorm.DB, userID uint) (*TwoFactorRes, error) {
+	var current UserTOTP
+	err := db.First(&current, "id_user = ? AND enabled_at IS NOT NULL", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &TwoFactorRes{Enabled: false}, nil
+	}
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var left int64
+	if err := db.Model(&RecoveryCode{}).Where("id_user = ? AND used_at IS NULL", userID).Count(&left).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return &TwoFactorRes{
+		Enabled:           true,
+		EnabledAt:         current.EnabledAt,
+		RecoveryCodesLeft: int(left),
+	}, nil
+}
+
+// checkSecondFactor accepts a code of the authenticator app or, for anything that
+// isn't a 6 digit code, an unused recovery code which is used up.
+func (s *service) checkSecondFactor(tx *gorm.DB, userID uint, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.DIGITS && strings.Trim(code, "0123456789") == "" {
+		var current UserTOTP
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&current, "id_user = ? AND enabled_at IS NOT NULL", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, apierror.NewWarn(http.StatusBadRequest, ErrTwoFactorDisabled)
+			}
+			return false, err
+		}
+		return s.checkTOTP(tx, &current, code)
+	}
+
+	result := tx.Model(&RecoveryCode{}).
+		Where("id_user = ? AND code_hash = ? AND used_at IS NULL", userID, s.hashOTP(userID, OTP_RECOVERY_CODE, normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// checkTOTP validates code and remembers its time step, a code can't be replayed.
+// current has to be locked by the caller.
+func (s *service) checkTOTP(tx *gorm.DB, current *UserTOTP, code string) (bool, error) {
+	secret, err := s.openSecret(current.SecretEnc)
+	if err != nil {
+		return false, err
+	}
+
+	counter, ok, err := totp.Validate(secret, code, time.Now(), TOTP_SKEW)
+	if err != nil || !ok || counter <= current.LastCounter {
+		return false, err
+	}
+
+	return true, tx.Model(current).Update("last_counter", counter).Error
+}
+
+func (s *service) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("id_user = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, s.authConfig.TwoFactor.RecoveryCodes)
+	rows := make([]RecoveryCode, 0, s.authConfig.TwoFactor.RecoveryCodes)
+	for range s.authConfig.TwoFactor.RecoveryCodes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, RecoveryCode{
+			IdUser:        userID,
+			CodeHash:      s.hashOTP(userID, OTP_RECOVERY_CODE, normalizeRecoveryCode(code)),
+			CreatedAtDate: time.Now(),
+		})
+	}
+
+	if len(rows) > 0 {
+		if err := tx.Create(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// newRecoveryCode returns 50 random bits as xxxxx-xxxxx.
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// sealSecret encrypts a TOTP secret with AES-GCM, unlike codes the secret has to be
+// read back to compute the expected code.
+func (s *service) sealSecret(secret string) (string, error) {
+	gcm, err := secretCipher(s.authConfig.EncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s *service) openSecret(sealed string) (string, error) {
+	gcm, err := secretCipher(s.authConfig.EncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid sealed totp secret")
+	}
+
+	secret, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to open totp secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+func secretCipher(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte("totp:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
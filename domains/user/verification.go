@@ -0,0 +1,114 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
+	"gorm.io/gorm"
+)
+
+// contactOTP is how a contact is verified: the otp tujuan, the notifier channel and
+// the user column holding the verification time.
+type contactOTP struct {
+	tujuan string
+	kanal  string
+	column string
+}
+
+var contactOTPs = map[string]contactOTP{
+	CONTACT_PHONE: {tujuan: OTP_VERIFY_PHONE, kanal: notifier.CHANNEL_SMS, column: "notelp_verified_at"},
+	CONTACT_EMAIL: {tujuan: OTP_VERIFY_EMAIL, kanal: notifier.CHANNEL_EMAIL, column: "email_verified_at"},
+}
+
+func (s *service) SendVerification(ctx context.Context, contact string) (*OTPSentRes, error) {
+	verify, ok := contactOTPs[contact]
+	if !ok {
+		return nil, apierror.NewWarn(http.StatusNotFound, fmt.Sprintf(ErrUnknownContact, contact))
+	}
+
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	to, verifiedAt := user.Notelp, user.NotelpVerifiedAt
+	if contact == CONTACT_EMAIL {
+		to, verifiedAt = user.Email, user.EmailVerifiedAt
+	}
+	if to == "" {
+		return nil, apierror.NewWarn(http.StatusBadRequest, ErrNoEmail)
+	}
+	if verifiedAt != nil {
+		return nil, apierror.NewWarn(http.StatusConflict, fmt.Sprintf(ErrAlreadyVerified, contact))
+	}
+
+	if err := s.sendOTP(ctx, *user, verify.tujuan, verify.kanal, to, "Verifikasi akun"); err != nil {
+		return nil, err
+	}
+
+	return &OTPSentRes{
+		Kanal:    verify.kanal,
+		ExpireIn: int(s.authConfig.OTP.ExpireIn.Seconds()),
+	}, nil
+}
+
+// VerifyContact marks the phone number or email verified with the code SendVerification
+// sent. Changing the contact in UpdateProfile voids pending codes, so a code only ever
+// verifies the address it was sent to.
+func (s *service) VerifyContact(ctx context.Context, contact string, input VerifyContactReq) (*VerifyContactRes, error) {
+	verify, ok := contactOTPs[contact]
+	if !ok {
+		return nil, apierror.NewWarn(http.StatusNotFound, fmt.Sprintf(ErrUnknownContact, contact))
+	}
+
+	user, err := s.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.useOTP(ctx, user.ID, verify.tujuan, input.Kode, func(tx *gorm.DB) error {
+		return tx.Model(&User{}).Where("id = ?", user.ID).Update(verify.column, now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.access.forget(user.ID)
+	return &VerifyContactRes{
+		Kontak:     contact,
+		VerifiedAt: now,
+	}, nil
+}
+
+// unverifyContact drops the verification of a changed contact along with the codes
+// sent to the old address.
+func unverifyContact(tx *gorm.DB, userID uint, contact string) error {
+	verify := contactOTPs[contact]
+	if err := tx.Model(&User{}).Where("id = ?", userID).Update(verify.column, nil).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return tx.Model(&OTP{}).
+		Where("id_user = ? AND tujuan = ? AND used_at IS NULL AND expires > ?", userID, verify.tujuan, now).
+		Update("expires", now).Error
+}
+
+func (s *service) currentUser(ctx context.Context) (*User, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	var user User
+	if err := s.db.WithContext(ctx).First(&user, "id = ?", token.Claims.ID).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,15 @@
+package wishlist
+
+// Availability of a saved product, resolved when the wishlist is read
+const (
+	STATUS_AVAILABLE    = "available"
+	STATUS_OUT_OF_STOCK = "out_of_stock"
+	STATUS_DELETED      = "deleted"
+)
+
+const (
+	ErrProductNotFound    = "Failed, product not found"
+	ErrNotInWishlist      = "Failed, product is not in your wishlist"
+	ErrProductUnavailable = "Product is no longer available"
+	ErrNotEnoughStock     = "Not enough stock for the requested quantity"
+)
@@ -0,0 +1,121 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/common"
+	"github.com/devanadindraa/Evermos-Backend/utils/respond"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Handler interface {
+	AddWishlist(ctx *fiber.Ctx) error
+	GetMyWishlist(ctx *fiber.Ctx) error
+	DeleteWishlist(ctx *fiber.Ctx) error
+	CheckoutWishlist(ctx *fiber.Ctx) error
+}
+
+type handler struct {
+	service  Service
+	validate *validator.Validate
+}
+
+func NewHandler(service Service, validate *validator.Validate) Handler {
+	return &handler{
+		service:  service,
+		validate: validate,
+	}
+}
+
+func (h *handler) AddWishlist(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	var input WishlistReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.AddWishlist(reqCtx, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
+
+func (h *handler) GetMyWishlist(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+
+	filter, err := common.GetMetaData(ctx, h.validate, "created_at_date", "nama_produk")
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	res, err := h.service.GetMyWishlist(reqCtx, filter)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to GET data", res)
+	return nil
+}
+
+func (h *handler) DeleteWishlist(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id_produk")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id_produk is required"))
+		return nil
+	}
+
+	if err := h.service.DeleteWishlist(reqCtx, productID); err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusOK, "Succeed to DELETE data", nil)
+	return nil
+}
+
+func (h *handler) CheckoutWishlist(ctx *fiber.Ctx) error {
+	reqCtx := ctx.Locals("ctx").(context.Context)
+	productID := ctx.Params("id_produk")
+	if productID == "" {
+		respond.Error(ctx, fmt.Errorf("id_produk is required"))
+		return nil
+	}
+
+	var input CheckoutReq
+	if err := ctx.BodyParser(&input); err != nil {
+		respond.Error(ctx, apierror.Warn(http.StatusBadRequest, err))
+		return nil
+	}
+
+	if err := h.validate.Struct(input); err != nil {
+		respond.Error(ctx, apierror.FromErr(err))
+		return nil
+	}
+
+	res, err := h.service.CheckoutWishlist(reqCtx, productID, input)
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+
+	respond.Success(ctx, http.StatusCreated, "Succeed to POST data", res)
+	return nil
+}
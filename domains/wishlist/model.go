@@ -0,0 +1,16 @@
+package wishlist
+
+import "time"
+
+type Wishlist struct {
+	ID            uint      `gorm:"primaryKey"`
+	IdUser        uint      `gorm:"not null"`
+	IdProduk      uint      `gorm:"not null"`
+	NamaProduk    string    `json:"nama_produk"`
+	CreatedAtDate time.Time `gorm:"autoCreateTime"`
+	UpdatedAtDate time.Time `gorm:"autoUpdateTime"`
+}
+
+func (Wishlist) TableName() string {
+	return "wishlist"
+}
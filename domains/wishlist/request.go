@@ -0,0 +1,11 @@
+package wishlist
+
+type WishlistReq struct {
+	IdProduk uint `json:"product_id" validate:"required"`
+}
+
+type CheckoutReq struct {
+	Kuantitas   int    `json:"kuantitas" validate:"required,gte=1"`
+	AlamatKirim int    `json:"alamat_kirim" validate:"required"`
+	MethodBayar string `json:"method_bayar" validate:"required"`
+}
@@ -0,0 +1,18 @@
+package wishlist
+
+import (
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/domains/product"
+)
+
+type WishlistRes struct {
+	ID            int               `json:"id"`
+	IdProduk      int               `json:"product_id"`
+	NamaProduk    string            `json:"nama_produk"`
+	HargaKonsumen *string           `json:"harga_konsumen,omitempty"`
+	Stok          *int              `json:"stok,omitempty"`
+	Status        string            `json:"status"`
+	Photo         *product.PhotoRes `json:"photo,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
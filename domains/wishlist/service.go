@@ -0,0 +1,219 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/domains/product"
+	"github.com/devanadindraa/Evermos-Backend/domains/trx"
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/constants"
+	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Service interface {
+	AddWishlist(ctx context.Context, input WishlistReq) (res *WishlistRes, err error)
+	GetMyWishlist(ctx context.Context, filter *constants.FilterReq) ([]WishlistRes, error)
+	DeleteWishlist(ctx context.Context, productID string) error
+	CheckoutWishlist(ctx context.Context, productID string, input CheckoutReq) (res *trx.Trx, err error)
+}
+
+type service struct {
+	authConfig config.Auth
+	db         *gorm.DB
+	store      storage.Store
+	trxService trx.Service
+}
+
+func NewService(config *config.Config, db *gorm.DB, store storage.Store, trxService trx.Service) Service {
+	return &service{
+		authConfig: config.Auth,
+		db:         db,
+		store:      store,
+		trxService: trxService,
+	}
+}
+
+func (s *service) AddWishlist(ctx context.Context, input WishlistReq) (res *WishlistRes, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	userID := uint(token.Claims.ID)
+
+	var produk product.Product
+	if err := s.db.WithContext(ctx).First(&produk, "id = ?", input.IdProduk).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrProductNotFound)
+	}
+
+	item := Wishlist{
+		IdUser:        userID,
+		IdProduk:      produk.ID,
+		NamaProduk:    produk.NamaProduk,
+		CreatedAtDate: time.Now(),
+		UpdatedAtDate: time.Now(),
+	}
+
+	// saving the same product twice is a no-op, not an error
+	if err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&item).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	if err := s.db.WithContext(ctx).First(&item, "id_user = ? AND id_produk = ?", userID, produk.ID).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	items, err := s.toWishlistRes(ctx, []Wishlist{item})
+	if err != nil {
+		return nil, err
+	}
+
+	return &items[0], nil
+}
+
+func (s *service) GetMyWishlist(ctx context.Context, filter *constants.FilterReq) ([]WishlistRes, error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	userID := token.Claims.ID
+
+	query := s.db.WithContext(ctx).Where("id_user = ?", userID)
+	if filter.Keyword != "" {
+		query = query.Where("nama_produk LIKE ?", "%"+filter.Keyword+"%")
+	}
+
+	var items []Wishlist
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Order(fmt.Sprintf("%s %s", filter.OrderBy, filter.SortOrder)).
+		Limit(int(filter.Limit)).
+		Offset(int(offset)).
+		Find(&items).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return s.toWishlistRes(ctx, items)
+}
+
+func (s *service) DeleteWishlist(ctx context.Context, productID string) error {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return apierror.FromErr(err)
+	}
+	userID := token.Claims.ID
+
+	result := s.db.WithContext(ctx).Where("id_user = ? AND id_produk = ?", userID, productID).Delete(&Wishlist{})
+	if result.Error != nil {
+		return apierror.FromErr(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apierror.NewWarn(http.StatusNotFound, ErrNotInWishlist)
+	}
+
+	return nil
+}
+
+// CheckoutWishlist turns a saved product into a new transaction and drops it from the wishlist.
+func (s *service) CheckoutWishlist(ctx context.Context, productID string, input CheckoutReq) (res *trx.Trx, err error) {
+	token, err := contextUtil.GetTokenClaims(ctx)
+	if err != nil {
+		return nil, apierror.FromErr(err)
+	}
+	userID := token.Claims.ID
+
+	var item Wishlist
+	if err := s.db.WithContext(ctx).First(&item, "id_user = ? AND id_produk = ?", userID, productID).Error; err != nil {
+		return nil, apierror.NewWarn(http.StatusNotFound, ErrNotInWishlist)
+	}
+
+	var produk product.Product
+	if err := s.db.WithContext(ctx).First(&produk, "id = ?", item.IdProduk).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierror.NewWarn(http.StatusConflict, ErrProductUnavailable)
+		}
+		return nil, apierror.FromErr(err)
+	}
+	if produk.Stok < input.Kuantitas {
+		return nil, apierror.NewWarn(http.StatusConflict, ErrNotEnoughStock)
+	}
+
+	res, err = s.trxService.AddTrx(ctx, trx.TrxReq{
+		MethodBayar: strings.TrimSpace(input.MethodBayar),
+		AlamatKirim: input.AlamatKirim,
+		DetailTrx: []trx.DetailTrxReq{
+			{ProdukId: int(produk.ID), Kuantitas: input.Kuantitas},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(&item).Error; err != nil {
+		return nil, apierror.FromErr(err)
+	}
+
+	return res, nil
+}
+
+// toWishlistRes resolves live price, stock and availability from the current products.
+func (s *service) toWishlistRes(ctx context.Context, items []Wishlist) ([]WishlistRes, error) {
+	productIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.IdProduk)
+	}
+
+	products := map[uint]product.Product{}
+	if len(productIDs) > 0 {
+		var found []product.Product
+		if err := s.db.WithContext(ctx).
+			Preload("Photos", product.OrderPhotos).
+			Find(&found, "id IN ?", productIDs).Error; err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		if _, err := product.ApplyEffectivePrices(s.db.WithContext(ctx), found, time.Now()); err != nil {
+			return nil, apierror.FromErr(err)
+		}
+		for _, p := range found {
+			products[p.ID] = p
+		}
+	}
+
+	result := []WishlistRes{}
+	for _, item := range items {
+		res := WishlistRes{
+			ID:         int(item.ID),
+			IdProduk:   int(item.IdProduk),
+			NamaProduk: item.NamaProduk,
+			Status:     STATUS_DELETED,
+			CreatedAt:  item.CreatedAtDate,
+		}
+
+		if p, ok := products[item.IdProduk]; ok {
+			res.NamaProduk = p.NamaProduk
+			res.HargaKonsumen = &p.HargaKonsumen
+			res.Stok = &p.Stok
+			res.Status = STATUS_AVAILABLE
+			if p.Stok <= 0 {
+				res.Status = STATUS_OUT_OF_STOCK
+			}
+			if photos := product.ToPhotoRes(s.store, p.Photos); len(photos) > 0 {
+				res.Photo = &photos[0]
+			}
+		}
+
+		result = append(result, res)
+	}
+
+	return result, nil
+}
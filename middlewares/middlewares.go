@@ -5,8 +5,10 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,10 +17,10 @@ import (
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"github.com/devanadindraa/Evermos-Backend/utils/constants"
 	contextUtil "github.com/devanadindraa/Evermos-Backend/utils/context"
+	"github.com/devanadindraa/Evermos-Backend/utils/keyset"
 	"github.com/devanadindraa/Evermos-Backend/utils/logger"
 	"github.com/devanadindraa/Evermos-Backend/utils/respond"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"golang.org/x/time/rate"
 )
@@ -27,7 +29,10 @@ type Middlewares interface {
 	AddRequestId(ctx *fiber.Ctx) error
 	Logging(ctx *fiber.Ctx) error
 	BasicAuth(ctx *fiber.Ctx) error
-	JWT(requireAdmin bool) fiber.Handler
+	JWT(ctx *fiber.Ctx) error
+	Require(permissions ...string) fiber.Handler
+	OptionalJWT(ctx *fiber.Ctx) error
+	RequireVerifiedPhone(ctx *fiber.Ctx) error
 	Recover(ctx *fiber.Ctx) error
 	RateLimiter(ctx *fiber.Ctx) error
 }
@@ -36,14 +41,16 @@ type middlewares struct {
 	conf        *config.Config
 	rateLimiter *rate.Limiter
 	userService user.Service
+	keys        *keyset.KeySet
 }
 
 // Constructor untuk middlewares
-func NewMiddlewares(conf *config.Config, userService user.Service) Middlewares {
+func NewMiddlewares(conf *config.Config, userService user.Service, keys *keyset.KeySet) Middlewares {
 	return &middlewares{
 		conf:        conf,
 		rateLimiter: rate.NewLimiter(rate.Limit(conf.RateLimiter.Rps), conf.RateLimiter.Bursts),
 		userService: userService,
+		keys:        keys,
 	}
 }
 
@@ -132,50 +139,163 @@ func (m *middlewares) BasicAuth(ctx *fiber.Ctx) error {
 	return ctx.SendStatus(fiber.StatusUnauthorized)
 }
 
-func (m *middlewares) JWT(requireAdmin bool) fiber.Handler {
-	return func(ctx *fiber.Ctx) error {
-		authHeader := ctx.Get("Authorization")
-		if authHeader == "" {
-			authHeader = ctx.Get("Auth")
-		}
+// JWT lets any signed in user through, routes limited to admins or staff use Require.
+func (m *middlewares) JWT(ctx *fiber.Ctx) error {
+	token, err := m.authenticate(ctx)
+	if err != nil {
+		respond.Error(ctx, authError(err))
+		return nil
+	}
 
-		authorizationSplit := strings.Split(authHeader, " ")
-		if len(authorizationSplit) < 2 {
-			respond.Error(ctx, apierror.Unauthorized())
-			return nil
-		}
+	ctx.Locals("token", token)
 
-		tokenStr := authorizationSplit[1]
-		claims := constants.JWTClaims{}
+	return ctx.Next()
+}
 
-		token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(m.conf.Auth.JWT.SecretKey), nil
-		})
-		if err != nil || !token.Valid {
-			respond.Error(ctx, apierror.Unauthorized())
+// Require authenticates like JWT and then checks the caller's roles grant every
+// one of permissions, the roles are read from the database and not the token.
+func (m *middlewares) Require(permissions ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token, err := m.authenticate(ctx)
+		if err != nil {
+			respond.Error(ctx, authError(err))
 			return nil
 		}
 
-		err = m.userService.ValidateToken(ctx.Context(), tokenStr)
+		missing, err := m.userService.HasPermissions(ctx.Context(), uint(token.Claims.ID), permissions...)
 		if err != nil {
-			respond.Error(ctx, apierror.Unauthorized())
+			respond.Error(ctx, err)
 			return nil
 		}
-
-		if requireAdmin && !claims.IsAdmin {
-			respond.Error(ctx, apierror.NewWarn(http.StatusForbidden, "Access denied: admin only"))
+		if missing != "" {
+			respond.Error(ctx, apierror.NewWarn(http.StatusForbidden, fmt.Sprintf(user.ErrMissingPermission, missing)))
 			return nil
 		}
+		// the roles grant them, only the second factor is missing
+		for _, permission := range permissions {
+			if !token.Can(permission) {
+				respond.Error(ctx, apierror.NewWarn(http.StatusForbidden, user.ErrTwoFactorRequired))
+				return nil
+			}
+		}
 
-		ctx.Locals("token", constants.Token{
-			Token:  tokenStr,
-			Claims: claims,
-		})
+		ctx.Locals("token", token)
 
 		return ctx.Next()
 	}
 }
 
+// OptionalJWT is for public read routes: anonymous requests pass through,
+// a token that is sent must still be valid so the caller gets its own view.
+func (m *middlewares) OptionalJWT(ctx *fiber.Ctx) error {
+	if ctx.Get("Authorization") == "" && ctx.Get("Auth") == "" {
+		return ctx.Next()
+	}
+
+	token, err := m.authenticate(ctx)
+	if err != nil {
+		respond.Error(ctx, authError(err))
+		return nil
+	}
+
+	ctx.Locals("token", token)
+
+	return ctx.Next()
+}
+
+// secondFactorOK tells whether claims may use admin and staff rights, with
+// TwoFactor.RequireForAdmins those need a login that passed 2FA.
+func (m *middlewares) secondFactorOK(claims constants.JWTClaims) bool {
+	return !m.conf.Auth.TwoFactor.RequireForAdmins || claims.Mfa
+}
+
+// RequireVerifiedPhone goes after JWT or Require on routes that move money, like checkout.
+func (m *middlewares) RequireVerifiedPhone(ctx *fiber.Ctx) error {
+	token, ok := ctx.Locals("token").(constants.Token)
+	if !ok {
+		respond.Error(ctx, apierror.Unauthorized())
+		return nil
+	}
+
+	access, err := m.userService.Access(ctx.Context(), uint(token.Claims.ID))
+	if err != nil {
+		respond.Error(ctx, err)
+		return nil
+	}
+	if !access.PhoneVerified {
+		respond.Error(ctx, apierror.NewWarn(http.StatusForbidden, user.ErrPhoneNotVerified))
+		return nil
+	}
+
+	return ctx.Next()
+}
+
+func (m *middlewares) authenticate(ctx *fiber.Ctx) (constants.Token, error) {
+	authHeader := ctx.Get("Authorization")
+	if authHeader == "" {
+		authHeader = ctx.Get("Auth")
+	}
+
+	authorizationSplit := strings.Split(authHeader, " ")
+	if len(authorizationSplit) < 2 {
+		return constants.Token{}, apierror.Unauthorized()
+	}
+
+	tokenStr := authorizationSplit[1]
+	claims := constants.JWTClaims{}
+
+	token, err := m.keys.Parse(tokenStr, &claims)
+	if err != nil || !token.Valid {
+		return constants.Token{}, apierror.Unauthorized()
+	}
+
+	if err := m.userService.ValidateToken(ctx.Context(), claims); err != nil {
+		return constants.Token{}, err
+	}
+
+	// the admin flag and suspension come from the database, a demotion or suspension
+	// applies to tokens issued before it
+	access, err := m.userService.Access(ctx.Context(), uint(claims.ID))
+	if err != nil {
+		return constants.Token{}, err
+	}
+	if access.Suspended {
+		return constants.Token{}, errSuspended
+	}
+	// a password reset ends every session, iat only has second precision so a token
+	// from the second of the reset is refused too
+	if access.TokensValidAfter != nil &&
+		(claims.IssuedAt == nil || !claims.IssuedAt.Time.After(access.TokensValidAfter.Truncate(time.Second))) {
+		return constants.Token{}, apierror.Unauthorized()
+	}
+	// admin and staff rights are only used by a login that passed the second factor
+	// when TwoFactor.RequireForAdmins asks for one
+	permissions := access.Permissions
+	if !m.secondFactorOK(claims) {
+		permissions = slices.DeleteFunc(slices.Clone(permissions), func(p string) bool {
+			return !slices.Contains(user.BASE_PERMISSIONS, p)
+		})
+	}
+	claims.IsAdmin = access.IsAdmin && m.secondFactorOK(claims)
+	m.userService.TouchSession(claims.Family)
+
+	return constants.Token{
+		Token:       tokenStr,
+		Claims:      claims,
+		Permissions: permissions,
+	}, nil
+}
+
+var errSuspended = apierror.NewWarn(http.StatusForbidden, user.ErrAccountSuspended)
+
+// authError hides why a token was refused, only a suspended account is told so.
+func authError(err error) error {
+	if errors.Is(err, errSuspended) {
+		return err
+	}
+	return apierror.Unauthorized()
+}
+
 func (m *middlewares) Recover(ctx *fiber.Ctx) error {
 	defer func() {
 		if r := recover(); r != nil {
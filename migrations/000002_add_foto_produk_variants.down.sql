@@ -0,0 +1,4 @@
+ALTER TABLE foto_produk
+    DROP COLUMN url_thumb,
+    DROP COLUMN url_medium,
+    DROP COLUMN url_large;
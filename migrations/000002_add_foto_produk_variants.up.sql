@@ -0,0 +1,4 @@
+ALTER TABLE foto_produk
+    ADD COLUMN url_thumb VARCHAR(255) AFTER url,
+    ADD COLUMN url_medium VARCHAR(255) AFTER url_thumb,
+    ADD COLUMN url_large VARCHAR(255) AFTER url_medium;
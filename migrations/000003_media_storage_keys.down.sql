@@ -0,0 +1,5 @@
+UPDATE foto_produk SET url = CONCAT('/uploads/', url) WHERE url <> '' AND url NOT LIKE '/uploads/%';
+UPDATE foto_produk SET url_thumb = CONCAT('/uploads/', url_thumb) WHERE url_thumb <> '' AND url_thumb NOT LIKE '/uploads/%';
+UPDATE foto_produk SET url_medium = CONCAT('/uploads/', url_medium) WHERE url_medium <> '' AND url_medium NOT LIKE '/uploads/%';
+UPDATE foto_produk SET url_large = CONCAT('/uploads/', url_large) WHERE url_large <> '' AND url_large NOT LIKE '/uploads/%';
+UPDATE toko SET url_foto = CONCAT('/uploads/', url_foto) WHERE url_foto <> '' AND url_foto NOT LIKE '/uploads/%';
@@ -0,0 +1,7 @@
+-- Media columns now hold storage keys (e.g. products/1_123_thumb.jpg)
+-- instead of local paths, URLs are built by the configured storage driver.
+UPDATE foto_produk SET url = SUBSTRING(url, 10) WHERE url LIKE '/uploads/%';
+UPDATE foto_produk SET url_thumb = SUBSTRING(url_thumb, 10) WHERE url_thumb LIKE '/uploads/%';
+UPDATE foto_produk SET url_medium = SUBSTRING(url_medium, 10) WHERE url_medium LIKE '/uploads/%';
+UPDATE foto_produk SET url_large = SUBSTRING(url_large, 10) WHERE url_large LIKE '/uploads/%';
+UPDATE toko SET url_foto = SUBSTRING(url_foto, 10) WHERE url_foto LIKE '/uploads/%';
@@ -0,0 +1,3 @@
+ALTER TABLE foto_produk
+    DROP COLUMN urutan,
+    DROP COLUMN is_primary;
@@ -0,0 +1,9 @@
+ALTER TABLE foto_produk
+    ADD COLUMN urutan INT NOT NULL DEFAULT 0 AFTER url_large,
+    ADD COLUMN is_primary BOOLEAN NOT NULL DEFAULT FALSE AFTER urutan;
+
+-- keep the current upload order and make the first photo of every product primary
+UPDATE foto_produk SET urutan = id;
+UPDATE foto_produk f
+    JOIN (SELECT id_produk, MIN(id) AS id FROM foto_produk GROUP BY id_produk) p ON p.id = f.id
+SET f.is_primary = TRUE;
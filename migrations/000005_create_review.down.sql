@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS foto_review;
+DROP TABLE IF EXISTS review;
+
+ALTER TABLE detail_trx
+    DROP COLUMN status;
@@ -0,0 +1,44 @@
+-- order lines from before statuses existed were completed long ago
+ALTER TABLE detail_trx
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'delivered' AFTER harga_total;
+ALTER TABLE detail_trx
+    ALTER COLUMN status SET DEFAULT 'pending';
+
+-- TABEL REVIEW
+CREATE TABLE
+    review (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        id_detail_trx INT NOT NULL UNIQUE,
+        id_produk INT NULL,
+        id_toko INT NOT NULL,
+        rating TINYINT NOT NULL,
+        ulasan TEXT,
+        balasan TEXT,
+        balasan_at DATETIME NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'visible',
+        catatan_moderasi VARCHAR(255),
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        INDEX idx_review_produk (id_produk, status),
+        INDEX idx_review_toko (id_toko, status),
+        FOREIGN KEY (id_user) REFERENCES user (id),
+        FOREIGN KEY (id_detail_trx) REFERENCES detail_trx (id),
+        -- a deleted product keeps its reviews, they still count for the shop
+        FOREIGN KEY (id_produk) REFERENCES produk (id) ON DELETE SET NULL,
+        FOREIGN KEY (id_toko) REFERENCES toko (id),
+        CHECK (rating BETWEEN 1 AND 5)
+    );
+
+-- TABEL FOTO REVIEW
+CREATE TABLE
+    foto_review (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_review INT NOT NULL,
+        url VARCHAR(255),
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        FOREIGN KEY (id_review) REFERENCES review (id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+    );
@@ -0,0 +1,15 @@
+-- TABEL WISHLIST
+-- no foreign key to produk on purpose: a deleted product stays listed (as deleted)
+-- until the user removes it, nama_produk keeps the name it had when it was saved
+CREATE TABLE
+    wishlist (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        id_produk INT NOT NULL,
+        nama_produk VARCHAR(255),
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_wishlist_user_produk (id_user, id_produk),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
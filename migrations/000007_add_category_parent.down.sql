@@ -0,0 +1,3 @@
+ALTER TABLE category
+    DROP FOREIGN KEY fk_category_parent,
+    DROP COLUMN parent_id;
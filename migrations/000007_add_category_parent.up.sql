@@ -0,0 +1,3 @@
+ALTER TABLE category
+    ADD COLUMN parent_id INT NULL AFTER nama_category,
+    ADD CONSTRAINT fk_category_parent FOREIGN KEY (parent_id) REFERENCES category (id);
@@ -0,0 +1,5 @@
+ALTER TABLE log_produk
+    DROP FOREIGN KEY fk_log_produk_category;
+
+ALTER TABLE log_produk
+    ADD CONSTRAINT log_produk_ibfk_2 FOREIGN KEY (id_category) REFERENCES category (id);
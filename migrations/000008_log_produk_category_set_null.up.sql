@@ -0,0 +1,7 @@
+-- log_produk is a snapshot of a sold product, it must not stop a category from being deleted
+ALTER TABLE log_produk
+    DROP FOREIGN KEY log_produk_ibfk_2;
+
+ALTER TABLE log_produk
+    ADD CONSTRAINT fk_log_produk_category FOREIGN KEY (id_category) REFERENCES category (id)
+    ON DELETE SET NULL;
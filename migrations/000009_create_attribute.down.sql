@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS produk_attribute;
+DROP TABLE IF EXISTS category_attribute;
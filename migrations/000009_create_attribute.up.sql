@@ -0,0 +1,31 @@
+-- TABEL CATEGORY ATTRIBUTE
+CREATE TABLE
+    category_attribute (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_category INT NOT NULL,
+        kode VARCHAR(64) NOT NULL,
+        nama VARCHAR(255),
+        tipe VARCHAR(20) NOT NULL,
+        opsi TEXT,
+        wajib BOOLEAN NOT NULL DEFAULT FALSE,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_category_attribute (id_category, kode),
+        FOREIGN KEY (id_category) REFERENCES category (id)
+        ON DELETE CASCADE
+    );
+
+-- TABEL PRODUK ATTRIBUTE
+CREATE TABLE
+    produk_attribute (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_produk INT NOT NULL,
+        kode VARCHAR(64) NOT NULL,
+        nilai VARCHAR(255) NOT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_produk_attribute (id_produk, kode),
+        INDEX idx_produk_attribute_filter (kode, nilai),
+        FOREIGN KEY (id_produk) REFERENCES produk (id)
+        ON DELETE CASCADE
+    );
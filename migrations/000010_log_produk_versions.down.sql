@@ -0,0 +1,10 @@
+DELETE FROM log_produk WHERE jenis = 'version';
+
+ALTER TABLE log_produk
+    DROP FOREIGN KEY fk_log_produk_user,
+    DROP INDEX uq_log_produk_versi,
+    DROP COLUMN catatan,
+    DROP COLUMN id_user,
+    DROP COLUMN stok,
+    DROP COLUMN versi,
+    DROP COLUMN jenis;
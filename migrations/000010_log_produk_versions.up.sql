@@ -0,0 +1,10 @@
+-- log_produk now holds both checkout snapshots and product versions
+ALTER TABLE log_produk
+    ADD COLUMN jenis VARCHAR(20) NOT NULL DEFAULT 'checkout' AFTER id_category,
+    ADD COLUMN versi INT NULL AFTER jenis,
+    ADD COLUMN stok INT NULL AFTER versi,
+    ADD COLUMN id_user INT NULL AFTER stok,
+    ADD COLUMN catatan VARCHAR(255) AFTER id_user,
+    ADD UNIQUE KEY uq_log_produk_versi (id_produk, versi),
+    ADD CONSTRAINT fk_log_produk_user FOREIGN KEY (id_user) REFERENCES user (id)
+    ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE log_produk
+    DROP COLUMN id_harga_terjadwal;
+
+DROP TABLE IF EXISTS harga_terjadwal;
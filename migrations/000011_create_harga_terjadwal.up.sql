@@ -0,0 +1,22 @@
+-- TABEL HARGA TERJADWAL
+CREATE TABLE
+    harga_terjadwal (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_produk INT NOT NULL,
+        harga_konsumen INT NOT NULL,
+        harga_reseller INT NULL,
+        mulai DATETIME NOT NULL,
+        selesai DATETIME NULL,
+        id_user INT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        INDEX idx_harga_terjadwal_aktif (id_produk, mulai, selesai),
+        FOREIGN KEY (id_produk) REFERENCES produk (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE SET NULL
+    );
+
+-- the price rule a checkout snapshot was sold under, if any
+ALTER TABLE log_produk
+    ADD COLUMN id_harga_terjadwal INT NULL AFTER catatan;
@@ -0,0 +1,6 @@
+ALTER TABLE log_produk
+    DROP COLUMN id_flash_sale_item;
+
+DROP TABLE IF EXISTS flash_sale_pembelian;
+DROP TABLE IF EXISTS flash_sale_item;
+DROP TABLE IF EXISTS flash_sale;
@@ -0,0 +1,55 @@
+-- TABEL FLASH SALE
+CREATE TABLE
+    flash_sale (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        nama VARCHAR(255) NOT NULL,
+        mulai DATETIME NOT NULL,
+        selesai DATETIME NOT NULL,
+        id_user INT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        INDEX idx_flash_sale_waktu (mulai, selesai),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE SET NULL
+    );
+
+-- TABEL FLASH SALE ITEM
+CREATE TABLE
+    flash_sale_item (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_flash_sale INT NOT NULL,
+        id_produk INT NOT NULL,
+        harga INT NOT NULL,
+        kuota INT NOT NULL,
+        terjual INT NOT NULL DEFAULT 0,
+        batas_per_user INT NOT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_flash_sale_item (id_flash_sale, id_produk),
+        FOREIGN KEY (id_flash_sale) REFERENCES flash_sale (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_produk) REFERENCES produk (id)
+        ON DELETE CASCADE,
+        CHECK (terjual <= kuota)
+    );
+
+-- TABEL FLASH SALE PEMBELIAN
+CREATE TABLE
+    flash_sale_pembelian (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_flash_sale_item INT NOT NULL,
+        id_user INT NOT NULL,
+        id_trx INT NOT NULL,
+        kuantitas INT NOT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        INDEX idx_flash_sale_pembelian_user (id_flash_sale_item, id_user),
+        FOREIGN KEY (id_flash_sale_item) REFERENCES flash_sale_item (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_user) REFERENCES user (id),
+        FOREIGN KEY (id_trx) REFERENCES trx (id)
+    );
+
+-- the flash sale item a checkout snapshot was sold under, if any
+ALTER TABLE log_produk
+    ADD COLUMN id_flash_sale_item INT NULL AFTER id_harga_terjadwal;
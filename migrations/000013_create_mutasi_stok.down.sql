@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS mutasi_stok_no_delete;
+DROP TRIGGER IF EXISTS mutasi_stok_no_update;
+
+DROP TABLE IF EXISTS mutasi_stok;
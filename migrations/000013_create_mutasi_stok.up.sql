@@ -0,0 +1,31 @@
+-- TABEL MUTASI STOK
+-- every change of produk.stok, rows are never updated or deleted. There is no foreign key
+-- to produk so the ledger outlives deleted products.
+CREATE TABLE
+    mutasi_stok (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_produk INT NOT NULL,
+        jenis VARCHAR(32) NOT NULL,
+        kuantitas INT NOT NULL,
+        stok_setelah INT NOT NULL,
+        alasan VARCHAR(255) NOT NULL DEFAULT '',
+        referensi VARCHAR(255) NOT NULL DEFAULT '',
+        id_user INT NULL,
+        created_at_date DATETIME,
+        INDEX idx_mutasi_stok_produk (id_produk, id),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE SET NULL
+    );
+
+-- opening balance, so the ledger of existing products adds up to their current stock
+INSERT INTO mutasi_stok (id_produk, jenis, kuantitas, stok_setelah, alasan, created_at_date)
+SELECT id, 'correction', COALESCE(stok, 0), COALESCE(stok, 0), 'saldo awal', NOW()
+FROM produk;
+
+UPDATE produk SET stok = 0 WHERE stok IS NULL;
+
+CREATE TRIGGER mutasi_stok_no_update BEFORE UPDATE ON mutasi_stok
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'mutasi_stok is append only';
+
+CREATE TRIGGER mutasi_stok_no_delete BEFORE DELETE ON mutasi_stok
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'mutasi_stok is append only';
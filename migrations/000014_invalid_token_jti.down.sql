@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS invalid_token;
+
+CREATE TABLE invalid_token (
+    token VARCHAR(255) PRIMARY KEY,
+    expires TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
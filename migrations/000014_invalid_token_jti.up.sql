@@ -0,0 +1,13 @@
+-- revoked tokens are identified by their jti claim, the full token did not fit in
+-- VARCHAR(255). Rows of the old layout can't be matched to a jti and are dropped,
+-- tokens issued before this change carry no jti and are rejected anyway.
+DROP TABLE IF EXISTS invalid_token;
+
+CREATE TABLE
+    invalid_token (
+        jti VARCHAR(64) PRIMARY KEY,
+        id_user INT NULL,
+        expires DATETIME NOT NULL,
+        created_at_date DATETIME,
+        INDEX idx_invalid_token_expires (expires)
+    );
@@ -0,0 +1,19 @@
+-- TABEL REFRESH TOKEN
+-- only the sha256 of a refresh token is stored. Every refresh rotates the token within
+-- its family, presenting a used token again revokes the whole family.
+CREATE TABLE
+    refresh_token (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        family VARCHAR(64) NOT NULL,
+        token_hash CHAR(64) NOT NULL,
+        expires DATETIME NOT NULL,
+        used_at DATETIME NULL,
+        revoked_at DATETIME NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_refresh_token_hash (token_hash),
+        INDEX idx_refresh_token_family (family),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
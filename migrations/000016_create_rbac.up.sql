@@ -0,0 +1,83 @@
+-- TABEL ROLE
+CREATE TABLE
+    role (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        kode VARCHAR(64) NOT NULL,
+        nama VARCHAR(255) NOT NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_role_kode (kode)
+    );
+
+-- TABEL PERMISSION
+CREATE TABLE
+    permission (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        kode VARCHAR(64) NOT NULL,
+        deskripsi VARCHAR(255) NOT NULL DEFAULT '',
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_permission_kode (kode)
+    );
+
+-- TABEL ROLE PERMISSION
+CREATE TABLE
+    role_permission (
+        id_role INT NOT NULL,
+        id_permission INT NOT NULL,
+        PRIMARY KEY (id_role, id_permission),
+        FOREIGN KEY (id_role) REFERENCES role (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_permission) REFERENCES permission (id)
+        ON DELETE CASCADE
+    );
+
+-- TABEL USER ROLE
+CREATE TABLE
+    user_role (
+        id_user INT NOT NULL,
+        id_role INT NOT NULL,
+        id_pemberi INT NULL,
+        created_at_date DATETIME,
+        PRIMARY KEY (id_user, id_role),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_role) REFERENCES role (id)
+        ON DELETE CASCADE,
+        FOREIGN KEY (id_pemberi) REFERENCES user (id)
+        ON DELETE SET NULL
+    );
+
+INSERT INTO role (kode, nama, created_at_date, updated_at_date) VALUES
+    ('admin', 'Admin', NOW(), NOW()),
+    ('catalog_moderator', 'Catalog moderator', NOW(), NOW()),
+    ('finance', 'Finance', NOW(), NOW()),
+    ('support', 'Support', NOW(), NOW()),
+    ('seller', 'Seller', NOW(), NOW()),
+    ('reseller', 'Reseller', NOW(), NOW()),
+    ('buyer', 'Buyer', NOW(), NOW());
+
+INSERT INTO permission (kode, deskripsi, created_at_date, updated_at_date) VALUES
+    ('category.manage', 'Create, edit and delete categories and their attributes', NOW(), NOW()),
+    ('product.moderate', 'Revert products to an earlier version', NOW(), NOW()),
+    ('review.moderate', 'Hide and show reviews', NOW(), NOW()),
+    ('flash_sale.manage', 'Create and cancel flash sales', NOW(), NOW()),
+    ('role.manage', 'Assign and remove roles of users', NOW(), NOW());
+
+-- admin holds every permission
+INSERT INTO role_permission (id_role, id_permission)
+SELECT r.id, p.id FROM role r CROSS JOIN permission p WHERE r.kode = 'admin';
+
+INSERT INTO role_permission (id_role, id_permission)
+SELECT r.id, p.id FROM role r JOIN permission p
+    ON (r.kode = 'catalog_moderator' AND p.kode IN ('category.manage', 'product.moderate', 'review.moderate'))
+    OR (r.kode = 'finance' AND p.kode IN ('flash_sale.manage'))
+    OR (r.kode = 'support' AND p.kode IN ('review.moderate'));
+
+-- existing accounts: admins keep their powers, everyone else has a shop and buys
+INSERT INTO user_role (id_user, id_role, created_at_date)
+SELECT u.id, r.id, NOW() FROM user u JOIN role r ON r.kode = 'admin' WHERE u.isAdmin = 1;
+
+INSERT INTO user_role (id_user, id_role, created_at_date)
+SELECT u.id, r.id, NOW() FROM user u JOIN role r ON r.kode IN ('seller', 'buyer')
+WHERE u.isAdmin = 0 OR u.isAdmin IS NULL;
@@ -0,0 +1,5 @@
+DELETE FROM permission WHERE kode = 'user.manage';
+
+ALTER TABLE user
+    DROP COLUMN alasan_suspend,
+    DROP COLUMN suspended_at;
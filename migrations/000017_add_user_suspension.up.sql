@@ -0,0 +1,10 @@
+ALTER TABLE user
+    ADD COLUMN suspended_at DATETIME NULL AFTER isAdmin,
+    ADD COLUMN alasan_suspend VARCHAR(255) NOT NULL DEFAULT '' AFTER suspended_at;
+
+INSERT INTO permission (kode, deskripsi, created_at_date, updated_at_date) VALUES
+    ('user.manage', 'List, search, suspend and unsuspend users', NOW(), NOW());
+
+INSERT INTO role_permission (id_role, id_permission)
+SELECT r.id, p.id FROM role r JOIN permission p ON p.kode = 'user.manage'
+WHERE r.kode IN ('admin', 'support');
@@ -0,0 +1,3 @@
+ALTER TABLE user DROP COLUMN tokens_valid_after;
+
+DROP TABLE IF EXISTS otp;
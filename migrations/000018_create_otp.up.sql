@@ -0,0 +1,18 @@
+-- TABEL OTP
+CREATE TABLE
+    otp (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        tujuan VARCHAR(32) NOT NULL,
+        kanal VARCHAR(16) NOT NULL,
+        code_hash CHAR(64) NOT NULL,
+        expires DATETIME NOT NULL,
+        attempts INT NOT NULL DEFAULT 0,
+        used_at DATETIME NULL,
+        created_at_date DATETIME,
+        INDEX idx_otp_user_tujuan (id_user, tujuan),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
+
+ALTER TABLE user ADD COLUMN tokens_valid_after DATETIME NULL AFTER alasan_suspend;
@@ -0,0 +1,3 @@
+ALTER TABLE user
+    DROP COLUMN email_verified_at,
+    DROP COLUMN notelp_verified_at;
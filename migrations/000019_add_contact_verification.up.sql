@@ -0,0 +1,8 @@
+ALTER TABLE user
+    ADD COLUMN notelp_verified_at DATETIME NULL AFTER notelp,
+    ADD COLUMN email_verified_at DATETIME NULL AFTER email;
+
+-- accounts from before verification existed keep working, only new and changed
+-- contacts have to be verified
+UPDATE user SET notelp_verified_at = created_at_date WHERE notelp IS NOT NULL AND notelp <> '';
+UPDATE user SET email_verified_at = created_at_date WHERE email IS NOT NULL AND email <> '';
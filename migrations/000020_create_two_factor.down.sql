@@ -0,0 +1,5 @@
+ALTER TABLE refresh_token DROP COLUMN mfa;
+
+DROP TABLE IF EXISTS login_challenge;
+DROP TABLE IF EXISTS recovery_code;
+DROP TABLE IF EXISTS user_totp;
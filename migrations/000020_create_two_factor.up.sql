@@ -0,0 +1,45 @@
+-- TABEL USER TOTP
+-- the secret is encrypted, enabled_at stays empty until the first code was verified
+CREATE TABLE
+    user_totp (
+        id_user INT PRIMARY KEY,
+        secret_enc VARCHAR(255) NOT NULL,
+        enabled_at DATETIME NULL,
+        last_counter BIGINT NOT NULL DEFAULT 0,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
+
+-- TABEL RECOVERY CODE
+CREATE TABLE
+    recovery_code (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        code_hash CHAR(64) NOT NULL,
+        used_at DATETIME NULL,
+        created_at_date DATETIME,
+        INDEX idx_recovery_code_user (id_user),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
+
+-- TABEL LOGIN CHALLENGE
+-- issued after the password step of a login with 2FA, exchanged for tokens with a code
+CREATE TABLE
+    login_challenge (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        token_hash CHAR(64) NOT NULL,
+        expires DATETIME NOT NULL,
+        attempts INT NOT NULL DEFAULT 0,
+        used_at DATETIME NULL,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_login_challenge_hash (token_hash),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
+
+-- whether the login a refresh token family started from passed 2FA
+ALTER TABLE refresh_token ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT FALSE AFTER family;
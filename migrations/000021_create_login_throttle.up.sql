@@ -0,0 +1,26 @@
+-- TABEL LOGIN THROTTLE
+-- failed login counters, kunci is "notelp:<no telp>" for an account or "ip:<address>"
+CREATE TABLE
+    login_throttle (
+        kunci VARCHAR(128) PRIMARY KEY,
+        gagal INT NOT NULL DEFAULT 0,
+        terakhir_gagal DATETIME NULL,
+        locked_until DATETIME NULL,
+        updated_at_date DATETIME
+    );
+
+-- TABEL LOGIN AUDIT
+CREATE TABLE
+    login_audit (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NULL,
+        notelp VARCHAR(255) NOT NULL DEFAULT '',
+        ip VARCHAR(64) NOT NULL DEFAULT '',
+        hasil VARCHAR(16) NOT NULL,
+        keterangan VARCHAR(255) NOT NULL DEFAULT '',
+        id_pelaku INT NULL,
+        created_at_date DATETIME,
+        INDEX idx_login_audit_created (created_at_date),
+        INDEX idx_login_audit_user (id_user),
+        INDEX idx_login_audit_ip (ip)
+    );
@@ -0,0 +1,29 @@
+-- TABEL SESSION
+-- one row per login, i.e. per refresh token family. jti is the access token issued
+-- last, a refresh replaces it.
+CREATE TABLE
+    session (
+        id INT AUTO_INCREMENT PRIMARY KEY,
+        id_user INT NOT NULL,
+        family VARCHAR(64) NOT NULL,
+        jti VARCHAR(64) NOT NULL DEFAULT '',
+        access_expires DATETIME NULL,
+        user_agent VARCHAR(512) NOT NULL DEFAULT '',
+        ip VARCHAR(64) NOT NULL DEFAULT '',
+        expires DATETIME NOT NULL,
+        last_seen_at DATETIME NULL,
+        revoked_at DATETIME NULL,
+        updated_at_date DATETIME,
+        created_at_date DATETIME,
+        UNIQUE KEY uq_session_family (family),
+        INDEX idx_session_user (id_user),
+        FOREIGN KEY (id_user) REFERENCES user (id)
+        ON DELETE CASCADE
+    );
+
+-- logins from before sessions existed, device and address are unknown
+INSERT INTO session (id_user, family, expires, last_seen_at, created_at_date, updated_at_date)
+SELECT id_user, family, MAX(expires), MAX(created_at_date), MIN(created_at_date), NOW()
+FROM refresh_token
+WHERE revoked_at IS NULL AND used_at IS NULL AND expires > NOW()
+GROUP BY id_user, family;
@@ -0,0 +1,2 @@
+DELETE FROM permission WHERE kode IN ('product.sell', 'shop.manage', 'order.place', 'review.write',
+    'product.manage_any', 'order.manage_any', 'shop.manage_any');
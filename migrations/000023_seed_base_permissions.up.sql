@@ -0,0 +1,20 @@
+INSERT INTO permission (kode, deskripsi, created_at_date, updated_at_date) VALUES
+    ('product.sell', 'Create and edit products of the own shop', NOW(), NOW()),
+    ('shop.manage', 'Edit the own shop and reply to its reviews', NOW(), NOW()),
+    ('order.place', 'Check out and place orders', NOW(), NOW()),
+    ('review.write', 'Review bought products', NOW(), NOW()),
+    ('product.manage_any', 'Edit and delete products of every shop', NOW(), NOW()),
+    ('order.manage_any', 'See every order and update the status of its lines', NOW(), NOW()),
+    ('shop.manage_any', 'Edit every shop and see the full shop list', NOW(), NOW());
+
+-- admin holds every permission
+INSERT INTO role_permission (id_role, id_permission)
+SELECT r.id, p.id FROM role r JOIN permission p ON r.kode = 'admin'
+WHERE p.kode IN ('product.sell', 'shop.manage', 'order.place', 'review.write',
+    'product.manage_any', 'order.manage_any', 'shop.manage_any');
+
+INSERT INTO role_permission (id_role, id_permission)
+SELECT r.id, p.id FROM role r JOIN permission p
+    ON (r.kode = 'seller' AND p.kode IN ('product.sell', 'shop.manage'))
+    OR (r.kode = 'reseller' AND p.kode IN ('order.place', 'review.write'))
+    OR (r.kode = 'buyer' AND p.kode IN ('order.place', 'review.write'));
@@ -1,13 +1,19 @@
 package routes
 
 import (
+	"strings"
+
 	"github.com/devanadindraa/Evermos-Backend/domains/address"
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
+	"github.com/devanadindraa/Evermos-Backend/domains/media"
 	"github.com/devanadindraa/Evermos-Backend/domains/product"
 	"github.com/devanadindraa/Evermos-Backend/domains/provcity"
+	"github.com/devanadindraa/Evermos-Backend/domains/review"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
 	"github.com/devanadindraa/Evermos-Backend/domains/trx"
 	"github.com/devanadindraa/Evermos-Backend/domains/user"
+	"github.com/devanadindraa/Evermos-Backend/domains/wishlist"
 	"github.com/devanadindraa/Evermos-Backend/middlewares"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
 	"github.com/gofiber/fiber/v2"
@@ -27,9 +33,19 @@ func NewDependency(
 	addressHandler address.Handler,
 	productHandler product.Handler,
 	trxHandler trx.Handler,
+	reviewHandler review.Handler,
+	wishlistHandler wishlist.Handler,
+	flashSaleHandler flashsale.Handler,
+	mediaHandler media.Handler,
 ) *Dependency {
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             conf.Proxy.Header,
+		EnableTrustedProxyCheck: conf.Proxy.Header != "",
+		TrustedProxies:          conf.Proxy.TrustedProxies,
+		EnableIPValidation:      true,
+	})
+	app.Get("/.well-known/jwks.json", userHandler.JWKS)
 	router := app.Group("/api/v1")
 	router.Use(func(c *fiber.Ctx) error {
 		if c.Method() != fiber.MethodGet &&
@@ -41,34 +57,67 @@ func NewDependency(
 		return c.Next()
 	})
 
-	// middleware
-	{
-		router.Use(cors.New())
-		router.Use(mw.AddRequestId)
-		router.Use(mw.Logging)
-		router.Use(mw.RateLimiter)
-		router.Use(mw.Recover)
+	// middleware, the media route below goes through the same chain
+	chain := []fiber.Handler{cors.New(), mw.AddRequestId, mw.Logging, mw.RateLimiter, mw.Recover}
+	for _, handler := range chain {
+		router.Use(handler)
 	}
 
 	// domain auth
 	auth := router.Group("/auth")
 	{
 		auth.Post("/login", mw.BasicAuth, userHandler.Login)
-		auth.Get("/verify-token", mw.JWT(false), userHandler.VerifyToken)
-		auth.Post("/logout", mw.JWT(false), userHandler.Logout)
+		auth.Post("/login/2fa", mw.BasicAuth, userHandler.LoginTwoFactor)
+		auth.Post("/refresh", mw.BasicAuth, userHandler.Refresh)
+		auth.Get("/verify-token", mw.JWT, userHandler.VerifyToken)
+		auth.Post("/logout", mw.JWT, userHandler.Logout)
 		auth.Post("/register", mw.BasicAuth, userHandler.Register)
+		auth.Post("/forgot-password", mw.BasicAuth, userHandler.ForgotPassword)
+		auth.Post("/reset-password", mw.BasicAuth, userHandler.ResetPassword)
 	}
 
 	// domain user
-	user := router.Group("/user")
+	userGroup := router.Group("/user")
 	{
-		user.Put("", mw.JWT(false), userHandler.UpdateProfile)
-		user.Get("", mw.JWT(false), userHandler.GetProfile)
-		user.Post("/alamat", mw.JWT(false), addressHandler.AddAddress)
-		user.Get("/alamat", mw.JWT(false), addressHandler.GetMyAddress)
-		user.Get("/alamat/:id", mw.JWT(false), addressHandler.GetAddressByID)
-		user.Delete("/alamat/:id", mw.JWT(false), addressHandler.DeleteAddress)
-		user.Put("/alamat/:id", mw.JWT(false), addressHandler.UpdateAddress)
+		userGroup.Put("", mw.JWT, userHandler.UpdateProfile)
+		userGroup.Get("", mw.JWT, userHandler.GetProfile)
+		userGroup.Get("/sessions", mw.JWT, userHandler.GetSessions)
+		userGroup.Delete("/sessions", mw.JWT, userHandler.RevokeAllSessions)
+		userGroup.Delete("/sessions/:id", mw.JWT, userHandler.RevokeSession)
+		userGroup.Post("/verify/:contact/send", mw.JWT, userHandler.SendVerification)
+		userGroup.Post("/verify/:contact", mw.JWT, userHandler.VerifyContact)
+		userGroup.Get("/2fa", mw.JWT, userHandler.GetTwoFactor)
+		userGroup.Post("/2fa/enroll", mw.JWT, userHandler.EnrollTwoFactor)
+		userGroup.Post("/2fa/enable", mw.JWT, userHandler.EnableTwoFactor)
+		userGroup.Post("/2fa/disable", mw.JWT, userHandler.DisableTwoFactor)
+		userGroup.Post("/2fa/recovery-codes", mw.JWT, userHandler.RegenerateRecoveryCodes)
+		userGroup.Post("/alamat", mw.JWT, addressHandler.AddAddress)
+		userGroup.Get("/alamat", mw.JWT, addressHandler.GetMyAddress)
+		userGroup.Get("/alamat/:id", mw.JWT, addressHandler.GetAddressByID)
+		userGroup.Delete("/alamat/:id", mw.JWT, addressHandler.DeleteAddress)
+		userGroup.Put("/alamat/:id", mw.JWT, addressHandler.UpdateAddress)
+		userGroup.Post("/wishlist", mw.JWT, wishlistHandler.AddWishlist)
+		userGroup.Get("/wishlist", mw.JWT, wishlistHandler.GetMyWishlist)
+		userGroup.Delete("/wishlist/:id_produk", mw.JWT, wishlistHandler.DeleteWishlist)
+		userGroup.Post("/wishlist/:id_produk/checkout", mw.Require(user.PERM_ORDER_PLACE), mw.RequireVerifiedPhone, wishlistHandler.CheckoutWishlist)
+	}
+
+	// admin, user management and roles
+	admin := router.Group("/admin")
+	{
+		admin.Get("/roles", mw.Require(user.PERM_ROLE_MANAGE), userHandler.GetRoles)
+		admin.Get("/users", mw.Require(user.PERM_USER_MANAGE), userHandler.ListUsers)
+		admin.Get("/users/:id", mw.Require(user.PERM_USER_MANAGE), userHandler.GetUser)
+		admin.Post("/users/:id/suspend", mw.Require(user.PERM_USER_MANAGE), userHandler.SuspendUser)
+		admin.Delete("/users/:id/suspend", mw.Require(user.PERM_USER_MANAGE), userHandler.UnsuspendUser)
+		admin.Delete("/users/:id/lockout", mw.Require(user.PERM_USER_MANAGE), userHandler.UnlockUser)
+		admin.Delete("/lockouts/ip/:ip", mw.Require(user.PERM_USER_MANAGE), userHandler.UnlockIP)
+		admin.Get("/login-audit", mw.Require(user.PERM_USER_MANAGE), userHandler.ListLoginAudit)
+		admin.Post("/users/:id/promote", mw.Require(user.PERM_ROLE_MANAGE), userHandler.PromoteUser)
+		admin.Post("/users/:id/demote", mw.Require(user.PERM_ROLE_MANAGE), userHandler.DemoteUser)
+		admin.Get("/users/:id/roles", mw.Require(user.PERM_ROLE_MANAGE), userHandler.GetUserRoles)
+		admin.Post("/users/:id/roles", mw.Require(user.PERM_ROLE_MANAGE), userHandler.AssignRole)
+		admin.Delete("/users/:id/roles/:role", mw.Require(user.PERM_ROLE_MANAGE), userHandler.RemoveRole)
 	}
 
 	// domain provcity
@@ -80,42 +129,88 @@ func NewDependency(
 		provcity.Get("/detailcity/:city_id", provcityHandler.GetDetailCity)
 	}
 
-	// domain category
+	// domain category, reads are public and writes need category.manage
 	category := router.Group("/category")
 	{
-		category.Post("", mw.JWT(true), categoryHandler.AddCategory)
-		category.Get("", mw.JWT(true), categoryHandler.GetAllCategory)
-		category.Get("/:id", mw.JWT(true), categoryHandler.GetCategoryByID)
-		category.Delete("/:id", mw.JWT(true), categoryHandler.DeleteCategory)
-		category.Put("/:id", mw.JWT(true), categoryHandler.UpdateCategory)
+		category.Post("", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.AddCategory)
+		category.Get("", mw.OptionalJWT, categoryHandler.GetAllCategory)
+		category.Get("/tree", mw.OptionalJWT, categoryHandler.GetCategoryTree)
+		category.Get("/:id", mw.OptionalJWT, categoryHandler.GetCategoryByID)
+		category.Delete("/:id", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.DeleteCategory)
+		category.Put("/:id", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.UpdateCategory)
+		category.Get("/:id/attributes", mw.OptionalJWT, categoryHandler.GetAttributes)
+		category.Post("/:id/attributes", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.AddAttribute)
+		category.Put("/:id/attributes/:attribute_id", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.UpdateAttribute)
+		category.Delete("/:id/attributes/:attribute_id", mw.Require(user.PERM_CATEGORY_MANAGE), categoryHandler.DeleteAttribute)
 	}
 
 	// domain toko
 	shop := router.Group("/toko")
 	{
-		shop.Get("/my", mw.JWT(false), shopHandler.GetMyShop)
-		shop.Get("/:id_toko", mw.JWT(false), shopHandler.GetShopByID)
-		shop.Put("/:id_toko", mw.JWT(false), shopHandler.UpdateMyShop)
-		shop.Get("/", mw.JWT(false), shopHandler.GetAllShop)
+		shop.Get("/my", mw.JWT, shopHandler.GetMyShop)
+		shop.Get("/:id_toko", mw.OptionalJWT, shopHandler.GetShopByID)
+		shop.Put("/:id_toko", mw.Require(user.PERM_SHOP_MANAGE), shopHandler.UpdateMyShop)
+		shop.Get("/", mw.OptionalJWT, shopHandler.GetAllShop)
 	}
 
 	// domain produk
 	product := router.Group("/product")
 	{
-		product.Post("", mw.JWT(false), productHandler.AddProduct)
-		product.Get("/:id", mw.JWT(false), productHandler.GetProductByID)
-		product.Get("", mw.JWT(false), productHandler.GetProducts)
-		product.Delete("/:id", mw.JWT(false), productHandler.DeleteProduct)
-		product.Put("/:id", mw.JWT(false), productHandler.UpdateProduct)
+		product.Post("", mw.Require(user.PERM_PRODUCT_SELL), productHandler.AddProduct)
+		product.Post("/import", mw.Require(user.PERM_PRODUCT_SELL), productHandler.ImportProducts)
+		product.Get("/export", mw.JWT, productHandler.ExportProducts)
+		product.Get("/:id", mw.OptionalJWT, productHandler.GetProductByID)
+		product.Get("", mw.OptionalJWT, productHandler.GetProducts)
+		product.Delete("/:id", mw.Require(user.PERM_PRODUCT_SELL), productHandler.DeleteProduct)
+		product.Put("/:id", mw.Require(user.PERM_PRODUCT_SELL), productHandler.UpdateProduct)
+		product.Post("/:id/photos", mw.Require(user.PERM_PRODUCT_SELL), productHandler.AddPhoto)
+		product.Put("/:id/photos/order", mw.Require(user.PERM_PRODUCT_SELL), productHandler.ReorderPhotos)
+		product.Put("/:id/photos/:photo_id/primary", mw.Require(user.PERM_PRODUCT_SELL), productHandler.SetPrimaryPhoto)
+		product.Delete("/:id/photos/:photo_id", mw.Require(user.PERM_PRODUCT_SELL), productHandler.DeletePhoto)
+		product.Get("/:id/reviews", mw.OptionalJWT, reviewHandler.GetProductReviews)
+		product.Get("/:id/history", mw.JWT, productHandler.GetProductHistory)
+		product.Get("/:id/stock", mw.JWT, productHandler.GetStockLedger)
+		product.Post("/:id/stock", mw.Require(user.PERM_PRODUCT_SELL), productHandler.AdjustStock)
+		product.Post("/:id/history/:versi/revert", mw.Require(user.PERM_PRODUCT_MODERATE), productHandler.RevertProduct)
+		product.Get("/:id/prices", mw.JWT, productHandler.GetPriceRules)
+		product.Post("/:id/prices", mw.Require(user.PERM_PRODUCT_SELL), productHandler.AddPriceRule)
+		product.Delete("/:id/prices/:rule_id", mw.Require(user.PERM_PRODUCT_SELL), productHandler.DeletePriceRule)
 	}
 
 	// domain trx
 	trx := router.Group("/trx")
 	{
-		trx.Post("", mw.JWT(false), trxHandler.AddTrx)
-		trx.Get("/:id", mw.JWT(false), trxHandler.GetTrxByID)
-		trx.Get("", mw.JWT(false), trxHandler.GetTrx)
+		trx.Post("", mw.Require(user.PERM_ORDER_PLACE), mw.RequireVerifiedPhone, trxHandler.AddTrx)
+		trx.Get("/:id", mw.JWT, trxHandler.GetTrxByID)
+		trx.Get("", mw.JWT, trxHandler.GetTrx)
+		trx.Put("/detail/:id/status", mw.JWT, trxHandler.UpdateDetailStatus)
+	}
+
+	// domain review
+	review := router.Group("/review")
+	{
+		review.Post("", mw.Require(user.PERM_REVIEW_WRITE), reviewHandler.AddReview)
+		review.Put("/:id/reply", mw.Require(user.PERM_SHOP_MANAGE), reviewHandler.ReplyReview)
+		review.Put("/:id/moderate", mw.Require(user.PERM_REVIEW_MODERATE), reviewHandler.ModerateReview)
+	}
+
+	// domain flash sale
+	flashSale := router.Group("/flash-sale")
+	{
+		flashSale.Get("", mw.OptionalJWT, flashSaleHandler.GetFlashSales)
+		flashSale.Get("/:id", mw.OptionalJWT, flashSaleHandler.GetFlashSaleByID)
+		flashSale.Post("", mw.Require(user.PERM_FLASH_SALE_MANAGE), flashSaleHandler.AddFlashSale)
+		flashSale.Delete("/:id", mw.Require(user.PERM_FLASH_SALE_MANAGE), flashSaleHandler.DeleteFlashSale)
+	}
+
+	// uploaded media, served at the same base path storage.Store builds URLs with,
+	// keys under media.PRIVATE_PREFIX need a signed URL
+	mediaPath := "/uploads"
+	if strings.HasPrefix(conf.Storage.PublicBaseURL, "/") {
+		mediaPath = strings.TrimSuffix(conf.Storage.PublicBaseURL, "/")
 	}
+	mediaGroup := app.Group(mediaPath, chain...)
+	mediaGroup.Get("/*", mediaHandler.Serve)
 
 	app.Use(func(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
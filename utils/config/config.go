@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/devanadindraa/Evermos-Backend/utils/logger"
@@ -18,7 +19,10 @@ type Config struct {
 	Logger      Logger      `envconfig:"logger"`
 	Auth        Auth        `envconfig:"auth"`
 	RateLimiter RateLimiter `envconfig:"rate_limiter"`
+	Proxy       Proxy       `envconfig:"proxy"`
 	Emsifa      Emsifa      `envconfig:"emsifa"`
+	Storage     Storage     `envconfig:"storage"`
+	Notifier    Notifier    `envconfig:"notifier"`
 }
 
 type Database struct {
@@ -34,15 +38,75 @@ type Logger struct {
 }
 
 type Auth struct {
-	JWT   JWT   `envconfig:"jwt" validate:"required"`
-	Basic Basic `envconfig:"basic" validate:"required"`
+	JWT       JWT       `envconfig:"jwt" validate:"required"`
+	Basic     Basic     `envconfig:"basic" validate:"required"`
+	OTP       OTP       `envconfig:"otp"`
+	TwoFactor TwoFactor `envconfig:"two_factor"`
+	Lockout   Lockout   `envconfig:"lockout"`
+	// protects TOTP secrets and OTP hashes at rest, kept apart from the JWT secret so
+	// rotating one doesn't touch the other
+	EncryptionKey string `envconfig:"encryption_key" required:"true"`
 }
 
 type JWT struct {
 	Username  string        `envconfig:"username" validate:"required"`
 	Password  string        `envconfig:"password" validate:"required"`
-	ExpireIn  time.Duration `envconfig:"expire_in" default:"1000m"`
+	ExpireIn  time.Duration `envconfig:"expire_in" default:"15m"`
 	SecretKey string        `envconfig:"secret_key" validate:"required"`
+	// Lifetime of a refresh token, every refresh issues a new one
+	RefreshExpireIn time.Duration `envconfig:"refresh_expire_in" default:"720h"`
+	// kid of the key new tokens are signed with, empty keeps HS256 with SecretKey
+	SigningKeyID string `envconfig:"signing_key_id"`
+	// PEM keys (RSA or Ed25519) as kid:path pairs
+	KeyFiles map[string]string `envconfig:"key_files"`
+	// PEM keys as kid:base64(PEM) pairs, for deployments without files
+	Keys map[string]string `envconfig:"keys"`
+	// kid:RFC3339 time a key was retired, it keeps verifying for KeyGracePeriod after that.
+	// Once SigningKeyID is set the SecretKey (kid hs256) counts as retired at startup
+	// unless it is listed here
+	RetiredKeys    map[string]string `envconfig:"retired_keys"`
+	KeyGracePeriod time.Duration     `envconfig:"key_grace_period" default:"1h"`
+	// How long a "not revoked" lookup is trusted before invalid_token is asked again
+	RevocationCacheTTL time.Duration `envconfig:"revocation_cache_ttl" default:"30s"`
+	// How long the permissions of a user are cached by mw.Require
+	PermissionCacheTTL time.Duration `envconfig:"permission_cache_ttl" default:"30s"`
+	// How often expired rows are purged from invalid_token
+	RevocationPurgeInterval time.Duration `envconfig:"revocation_purge_interval" default:"1h"`
+	// How often the last seen times of sessions are written, requests only mark them in memory
+	LastSeenFlushInterval time.Duration `envconfig:"last_seen_flush_interval" default:"1m"`
+}
+
+// OTP codes sent by SMS or email, e.g. for password resets
+type OTP struct {
+	ExpireIn time.Duration `envconfig:"expire_in" default:"10m"`
+	// Wrong guesses after which a code stops working
+	MaxAttempts int `envconfig:"max_attempts" default:"5"`
+	// Minimum time between two codes for the same user and purpose
+	ResendInterval time.Duration `envconfig:"resend_interval" default:"1m"`
+}
+
+type TwoFactor struct {
+	// Issuer shown in authenticator apps
+	Issuer string `envconfig:"issuer" default:"Evermos"`
+	// Lifetime of the challenge token between the password and the code step of a login
+	ChallengeExpireIn time.Duration `envconfig:"challenge_expire_in" default:"5m"`
+	// Refuse admin and staff routes (mw.Require) to tokens from a login without 2FA
+	RequireForAdmins bool `envconfig:"require_for_admins" default:"false"`
+	RecoveryCodes    int  `envconfig:"recovery_codes" default:"10"`
+}
+
+// Lockout throttles failed logins per account and per IP. From BackoffAfter failures on
+// each attempt waits BackoffBase, doubling up to BackoffMax, and MaxFailures lock the
+// account or IP for LockoutDuration. Failures older than FailureWindow are forgotten.
+// Behind a reverse proxy the IP is only the client's once Proxy is set.
+type Lockout struct {
+	BackoffAfter       int           `envconfig:"backoff_after" default:"3"`
+	BackoffBase        time.Duration `envconfig:"backoff_base" default:"1s"`
+	BackoffMax         time.Duration `envconfig:"backoff_max" default:"5m"`
+	AccountMaxFailures int           `envconfig:"account_max_failures" default:"10"`
+	IPMaxFailures      int           `envconfig:"ip_max_failures" default:"50"`
+	LockoutDuration    time.Duration `envconfig:"lockout_duration" default:"15m"`
+	FailureWindow      time.Duration `envconfig:"failure_window" default:"1h"`
 }
 
 type Basic struct {
@@ -55,10 +119,55 @@ type RateLimiter struct {
 	Bursts int `envconfig:"bursts" default:"5"`
 }
 
+// Proxy tells where the client IP comes from when the app runs behind a reverse proxy
+// or load balancer. Without it ctx.IP() is the proxy's address and the per-IP login
+// lockout would lock every user out at once. Header must be one the proxy overwrites
+// (e.g. X-Real-IP), it is only read from TrustedProxies (IPs or CIDRs), requests from
+// anywhere else keep their remote address.
+type Proxy struct {
+	Header         string   `envconfig:"header"`
+	TrustedProxies []string `envconfig:"trusted_proxies"`
+}
+
 type Emsifa struct {
 	BaseUrl string `envconfig:"base_url"`
 }
 
+type Storage struct {
+	Driver        string       `envconfig:"driver" default:"local" validate:"oneof=local s3 memory"`
+	PublicBaseURL string       `envconfig:"public_base_url" default:"/uploads"`
+	SigningKey    string       `envconfig:"signing_key"`
+	Local         LocalStorage `envconfig:"local"`
+	S3            S3Storage    `envconfig:"s3"`
+}
+
+type LocalStorage struct {
+	Root string `envconfig:"root" default:"uploads"`
+}
+
+type S3Storage struct {
+	Endpoint      string        `envconfig:"endpoint"`
+	Region        string        `envconfig:"region" default:"us-east-1"`
+	Bucket        string        `envconfig:"bucket"`
+	AccessKey     string        `envconfig:"access_key"`
+	SecretKey     string        `envconfig:"secret_key"`
+	PathStyle     bool          `envconfig:"path_style" default:"true"`
+	PublicBaseURL string        `envconfig:"public_base_url"`
+	Timeout       time.Duration `envconfig:"timeout" default:"30s"`
+}
+
+type Notifier struct {
+	Driver string       `envconfig:"driver" default:"console" validate:"oneof=console file"`
+	File   FileNotifier `envconfig:"file"`
+}
+
+type FileNotifier struct {
+	Path string `envconfig:"path" default:"tmp/notifications.log"`
+}
+
+// MIN_ENCRYPTION_KEY_LENGTH keeps Auth.EncryptionKey from being a guessable password
+const MIN_ENCRYPTION_KEY_LENGTH = 32
+
 var config *Config
 
 func NewConfig() *Config {
@@ -72,6 +181,13 @@ func NewConfig() *Config {
 	if err != nil {
 		panic("Failed to Process env : " + err.Error())
 	}
+	if len(c.Auth.EncryptionKey) < MIN_ENCRYPTION_KEY_LENGTH {
+		panic(fmt.Sprintf("Failed to Process env : BACKEND_AUTH_ENCRYPTION_KEY must be at least %d characters", MIN_ENCRYPTION_KEY_LENGTH))
+	}
+
+	if c.Proxy.Header != "" && len(c.Proxy.TrustedProxies) == 0 {
+		panic("Failed to Process env : BACKEND_PROXY_HEADER needs BACKEND_PROXY_TRUSTED_PROXIES, any client could set it otherwise")
+	}
 
 	config = &c
 
@@ -2,6 +2,7 @@ package constants
 
 import (
 	"net/url"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -11,12 +12,23 @@ type JWTClaims struct {
 	ID      int    `json:"userID"`
 	IsAdmin bool   `json:"isAdmin"`
 	NoTelp  string `json:"no_telp"`
+	// refresh token family the access token was issued from
+	Family string `json:"family,omitempty"`
+	// the login passed a second factor
+	Mfa bool `json:"mfa,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type Token struct {
 	Token  string
 	Claims JWTClaims
+	// permissions of the caller's roles, read from the database on every request
+	Permissions []string
+}
+
+// Can tells whether the caller's roles grant permission.
+func (t Token) Can(permission string) bool {
+	return slices.Contains(t.Permissions, permission)
 }
 
 type FilterReq struct {
@@ -0,0 +1,170 @@
+package imageutils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	_ "image/gif"
+	_ "image/png"
+
+	apierror "github.com/devanadindraa/Evermos-Backend/utils/api-error"
+)
+
+const (
+	// Largest upload we are willing to read into memory
+	MaxUploadSize = 10 << 20
+	// Guard against decompression bombs (e.g. a tiny PNG declaring 50000x50000)
+	MaxPixels   = 40_000_000
+	JPEGQuality = 85
+)
+
+type Variant struct {
+	Name    string
+	MaxSide int
+}
+
+var ProductVariants = []Variant{
+	{Name: "thumb", MaxSide: 200},
+	{Name: "medium", MaxSide: 600},
+	{Name: "large", MaxSide: 1200},
+}
+
+var ReviewVariants = []Variant{
+	{Name: "large", MaxSide: 1200},
+}
+
+var ShopVariants = []Variant{
+	{Name: "large", MaxSide: 600},
+}
+
+var allowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+type Processed struct {
+	Variant Variant
+	Data    []byte
+}
+
+// Process decodes an uploaded image (sniffing the content, not the extension),
+// applies the EXIF orientation and re-encodes every variant as JPEG.
+// Re-encoding drops all metadata, so EXIF (GPS, camera serial, ...) is stripped.
+func Process(file *multipart.FileHeader, variants []Variant) ([]Processed, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, MaxUploadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	return ProcessBytes(file.Filename, data, variants)
+}
+
+func ProcessBytes(filename string, data []byte, variants []Variant) ([]Processed, error) {
+	if len(data) > MaxUploadSize {
+		return nil, apierror.NewWarn(http.StatusRequestEntityTooLarge, fmt.Sprintf("%s: image is larger than %d MB", filename, MaxUploadSize>>20))
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("%s: unsupported file type %s, only jpeg, png and gif are allowed", filename, contentType))
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("%s: invalid image: %v", filename, err))
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("%s: image dimension %dx%d is too large", filename, cfg.Width, cfg.Height))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apierror.NewWarn(http.StatusBadRequest, fmt.Sprintf("%s: invalid image: %v", filename, err))
+	}
+
+	// Flatten once onto a white background, JPEG has no alpha channel
+	base := flatten(img)
+	if contentType == "image/jpeg" {
+		base = applyOrientation(base, jpegOrientation(data))
+	}
+
+	res := make([]Processed, 0, len(variants))
+	for _, v := range variants {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Resize(base, v.MaxSide), &jpeg.Options{Quality: JPEGQuality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s variant: %w", v.Name, err)
+		}
+		res = append(res, Processed{Variant: v, Data: buf.Bytes()})
+	}
+
+	return res, nil
+}
+
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// Resize shrinks src so its longest side is at most maxSide, using area averaging.
+// Images that already fit are returned as is, we never upscale.
+func Resize(src *image.RGBA, maxSide int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= maxSide && sh <= maxSide {
+		return src
+	}
+
+	dw, dh := maxSide, maxSide
+	if sw >= sh {
+		dh = max(1, sh*maxSide/sw)
+	} else {
+		dw = max(1, sw*maxSide/sh)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0 := y * sh / dh
+		sy1 := max(sy0+1, (y+1)*sh/dh)
+		for x := 0; x < dw; x++ {
+			sx0 := x * sw / dw
+			sx1 := max(sx0+1, (x+1)*sw/dw)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				off := sy*src.Stride + sx0*4
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint64(src.Pix[off])
+					g += uint64(src.Pix[off+1])
+					b += uint64(src.Pix[off+2])
+					a += uint64(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
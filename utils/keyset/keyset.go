@@ -0,0 +1,246 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// HMAC_KEY_ID is the kid of the shared SecretKey. It signs while no signing key is
+// configured and is retired once one is, it can be named in RetiredKeys like any key.
+const HMAC_KEY_ID = "hs256"
+
+var ErrUnknownKey = errors.New("unknown or retired signing key")
+
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.PrivateKey
+	Public    crypto.PublicKey
+	RetiredAt *time.Time
+}
+
+// KeySet signs tokens with one key and verifies them with every key that is still
+// active, a retired key keeps verifying for the grace period after its retirement.
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	grace   time.Duration
+}
+
+func New(conf *config.Config) (*KeySet, error) {
+	jwtConf := conf.Auth.JWT
+	ks := &KeySet{
+		keys:  map[string]*Key{},
+		grace: jwtConf.KeyGracePeriod,
+	}
+
+	for kid, path := range jwtConf.KeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: %w", kid, err)
+		}
+		if err := ks.add(kid, data); err != nil {
+			return nil, err
+		}
+	}
+	for kid, encoded := range jwtConf.Keys {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %s: must be a base64 encoded PEM: %w", kid, err)
+		}
+		if err := ks.add(kid, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if jwtConf.SecretKey != "" {
+		secret := []byte(jwtConf.SecretKey)
+		ks.keys[HMAC_KEY_ID] = &Key{ID: HMAC_KEY_ID, Method: jwt.SigningMethodHS256, Private: secret, Public: secret}
+	}
+
+	for kid, at := range jwtConf.RetiredKeys {
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("retired jwt key %s is not configured", kid)
+		}
+		retiredAt, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return nil, fmt.Errorf("retired jwt key %s: %w", kid, err)
+		}
+		key.RetiredAt = &retiredAt
+	}
+
+	signingKeyID := jwtConf.SigningKeyID
+	// without asymmetric keys the shared secret keeps signing, as before
+	if signingKeyID == "" {
+		if jwtConf.SecretKey == "" {
+			return nil, fmt.Errorf("jwt: either a signing key id or a secret key is required")
+		}
+		signingKeyID = HMAC_KEY_ID
+	} else if hmac, ok := ks.keys[HMAC_KEY_ID]; ok && hmac.RetiredAt == nil {
+		// tokens signed before the switch keep verifying for the grace period, counted
+		// from startup unless RetiredKeys says when the secret stopped signing
+		retiredAt := time.Now()
+		hmac.RetiredAt = &retiredAt
+	}
+
+	signing, ok := ks.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt signing key %s is not configured", jwtConf.SigningKeyID)
+	}
+	if signing.Private == nil {
+		return nil, fmt.Errorf("jwt signing key %s has no private key", signing.ID)
+	}
+	if signing.RetiredAt != nil {
+		return nil, fmt.Errorf("jwt signing key %s is retired", signing.ID)
+	}
+	ks.signing = signing
+
+	return ks, nil
+}
+
+// add parses a PEM private or public key. Public keys can only verify, which is all a
+// retired key needs.
+func (ks *KeySet) add(kid string, data []byte) error {
+	if kid == "" || kid == HMAC_KEY_ID {
+		return fmt.Errorf("jwt key id %q is not allowed", kid)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("jwt key %s: no PEM block found", kid)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return fmt.Errorf("jwt key %s: unsupported PEM type %s", kid, block.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("jwt key %s: %w", kid, err)
+	}
+
+	key := &Key{ID: kid}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return fmt.Errorf("jwt key %s: only RSA and Ed25519 keys are supported", kid)
+	}
+
+	ks.keys[kid] = key
+	return nil
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != HMAC_KEY_ID {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.Private)
+}
+
+// Parse verifies tokenStr with the key named by its kid. The algorithm must be the one
+// of that key, so a public key can never be used as an HMAC secret.
+func (ks *KeySet) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = HMAC_KEY_ID
+		}
+
+		key, ok := ks.active(kid, time.Now())
+		if !ok || token.Method.Alg() != key.Method.Alg() {
+			return nil, ErrUnknownKey
+		}
+		return key.Public, nil
+	})
+}
+
+func (ks *KeySet) active(kid string, now time.Time) (*Key, bool) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, false
+	}
+	if key.RetiredAt != nil && now.After(key.RetiredAt.Add(ks.grace)) {
+		return nil, false
+	}
+	return key, true
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public part of every key that still verifies. The shared HMAC secret
+// is never published.
+func (ks *KeySet) JWKS() JWKSet {
+	now := time.Now()
+	res := JWKSet{Keys: []JWK{}}
+
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	for _, kid := range kids {
+		key, ok := ks.active(kid, now)
+		if !ok {
+			continue
+		}
+
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		res.Keys = append(res.Keys, jwk)
+	}
+
+	return res
+}
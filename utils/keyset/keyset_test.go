@@ -0,0 +1,247 @@
+package keyset
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "rahasia"
+
+var (
+	rsaKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _   = ed25519.GenerateKey(rand.Reader)
+	_, edOther, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+func encodePEM(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func testConfig(t *testing.T, signing string, retired map[string]string) *config.Config {
+	t.Helper()
+	conf := &config.Config{}
+	conf.Auth.JWT.SecretKey = testSecret
+	conf.Auth.JWT.SigningKeyID = signing
+	conf.Auth.JWT.KeyGracePeriod = time.Hour
+	conf.Auth.JWT.RetiredKeys = retired
+	conf.Auth.JWT.Keys = map[string]string{
+		"rsa-1": encodePEM(t, rsaKey),
+		"ed-1":  encodePEM(t, edKey),
+	}
+	return conf
+}
+
+func newKeySet(t *testing.T, conf *config.Config) *KeySet {
+	t.Helper()
+	ks, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ks
+}
+
+// signWith builds a token the way another signer would, kid "" leaves the header out.
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestSignUsesConfiguredKey(t *testing.T) {
+	tests := []struct {
+		signing string
+		alg     string
+		kid     any
+	}{
+		{"", "HS256", nil},
+		{"rsa-1", "RS256", "rsa-1"},
+		{"ed-1", "EdDSA", "ed-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			ks := newKeySet(t, testConfig(t, tt.signing, nil))
+
+			signed, err := ks.Sign(jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := ks.Parse(signed, &jwt.RegisteredClaims{})
+			if err != nil || !token.Valid {
+				t.Fatalf("Parse: %v", err)
+			}
+			if token.Method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", token.Method.Alg(), tt.alg)
+			}
+			if token.Header["kid"] != tt.kid {
+				t.Errorf("kid = %v, want %v", token.Header["kid"], tt.kid)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	rsaPublic := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+		ok    bool
+	}{
+		{"rsa key by kid", func(t *testing.T) string { return signWith(t, jwt.SigningMethodRS256, "rsa-1", rsaKey) }, true},
+		{"ed25519 key by kid", func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "ed-1", edKey) }, true},
+		{"unknown kid", func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "ed-2", edKey) }, false},
+		{"kid of another key", func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "ed-1", edOther) }, false},
+		{"alg of another key type", func(t *testing.T) string { return signWith(t, jwt.SigningMethodEdDSA, "rsa-1", edKey) }, false},
+		{"public key used as hmac secret", func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "rsa-1", rsaPublic) }, false},
+		{"hmac with an explicit hs256 kid", func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, HMAC_KEY_ID, []byte(testSecret)) }, true},
+		{"hmac without kid during the grace period", func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret)) }, true},
+		{"hmac with a wrong secret", func(t *testing.T) string { return signWith(t, jwt.SigningMethodHS256, "", []byte("other")) }, false},
+	}
+
+	ks := newKeySet(t, testConfig(t, "rsa-1", nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ks.Parse(tt.token(t), &jwt.RegisteredClaims{})
+			ok := err == nil && token.Valid
+			if ok != tt.ok {
+				t.Errorf("Parse ok = %v (err %v), want %v", ok, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRetiredKeyGrace(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name      string
+		retiredAt time.Time
+		ok        bool
+	}{
+		{"retired within the grace period", now.Add(-30 * time.Minute), true},
+		{"retired past the grace period", now.Add(-2 * time.Hour), false},
+		{"retirement in the future", now.Add(time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := tt.retiredAt.Format(time.RFC3339)
+			ks := newKeySet(t, testConfig(t, "ed-1", map[string]string{"rsa-1": at, HMAC_KEY_ID: at}))
+
+			rsaToken := signWith(t, jwt.SigningMethodRS256, "rsa-1", rsaKey)
+			if _, err := ks.Parse(rsaToken, &jwt.RegisteredClaims{}); (err == nil) != tt.ok {
+				t.Errorf("retired rsa key: err = %v, want ok %v", err, tt.ok)
+			}
+
+			hmacToken := signWith(t, jwt.SigningMethodHS256, "", []byte(testSecret))
+			if _, err := ks.Parse(hmacToken, &jwt.RegisteredClaims{}); (err == nil) != tt.ok {
+				t.Errorf("retired hmac secret: err = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewRejects(t *testing.T) {
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	tests := []struct {
+		name string
+		conf func(t *testing.T) *config.Config
+	}{
+		{"unknown signing key", func(t *testing.T) *config.Config { return testConfig(t, "rsa-2", nil) }},
+		{"retired signing key", func(t *testing.T) *config.Config { return testConfig(t, "rsa-1", map[string]string{"rsa-1": past}) }},
+		{"retired hmac secret still signing", func(t *testing.T) *config.Config {
+			return testConfig(t, "", map[string]string{HMAC_KEY_ID: past})
+		}},
+		{"unknown retired key", func(t *testing.T) *config.Config { return testConfig(t, "rsa-1", map[string]string{"rsa-2": past}) }},
+		{"nothing to sign with", func(t *testing.T) *config.Config {
+			conf := testConfig(t, "", nil)
+			conf.Auth.JWT.SecretKey = ""
+			return conf
+		}},
+		{"reserved kid", func(t *testing.T) *config.Config {
+			conf := testConfig(t, "rsa-1", nil)
+			conf.Auth.JWT.Keys[HMAC_KEY_ID] = conf.Auth.JWT.Keys["ed-1"]
+			return conf
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.conf(t)); err == nil {
+				t.Error("New succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	past := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name    string
+		signing string
+		retired map[string]string
+		want    []string
+	}{
+		{"hmac only signer publishes the asymmetric keys", "", nil, []string{"ed-1", "rsa-1"}},
+		{"active keys sorted by kid", "rsa-1", nil, []string{"ed-1", "rsa-1"}},
+		{"key past its grace period is dropped", "rsa-1", map[string]string{"ed-1": past}, []string{"rsa-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newKeySet(t, testConfig(t, tt.signing, tt.retired)).JWKS()
+
+			var kids []string
+			for _, jwk := range set.Keys {
+				kids = append(kids, jwk.Kid)
+				if jwk.Kid == HMAC_KEY_ID || jwk.Alg == "HS256" {
+					t.Errorf("the hmac secret was published")
+				}
+				switch jwk.Kid {
+				case "rsa-1":
+					if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.N == "" || jwk.E != "AQAB" {
+						t.Errorf("rsa jwk = %+v", jwk)
+					}
+				case "ed-1":
+					want := base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey))
+					if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != "EdDSA" || jwk.X != want {
+						t.Errorf("ed25519 jwk = %+v", jwk)
+					}
+				}
+			}
+
+			if len(kids) != len(tt.want) {
+				t.Fatalf("kids = %v, want %v", kids, tt.want)
+			}
+			for i := range kids {
+				if kids[i] != tt.want[i] {
+					t.Errorf("kids = %v, want %v", kids, tt.want)
+				}
+			}
+		})
+	}
+}
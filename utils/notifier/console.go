@@ -0,0 +1,19 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/logger"
+)
+
+// consoleNotifier logs messages instead of sending them, for local development.
+type consoleNotifier struct{}
+
+func NewConsoleNotifier() Notifier {
+	return consoleNotifier{}
+}
+
+func (consoleNotifier) Send(ctx context.Context, msg Message) error {
+	logger.Info(ctx, "notifier: %s to %s: %s %s", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
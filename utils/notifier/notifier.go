@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+)
+
+const (
+	DRIVER_CONSOLE = "console"
+	DRIVER_FILE    = "file"
+)
+
+const (
+	CHANNEL_SMS   = "sms"
+	CHANNEL_EMAIL = "email"
+)
+
+// Message is a one-off text for a user, To is a phone number for CHANNEL_SMS and an
+// address for CHANNEL_EMAIL.
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to users. Real SMS and email gateways plug in here, the
+// bundled drivers only write the messages somewhere a developer can read them.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func New(conf *config.Config) (Notifier, error) {
+	notifierConf := conf.Notifier
+
+	switch notifierConf.Driver {
+	case DRIVER_CONSOLE, "":
+		return NewConsoleNotifier(), nil
+	case DRIVER_FILE:
+		return NewFileNotifier(notifierConf.File.Path), nil
+	}
+
+	return nil, fmt.Errorf("unknown notifier driver %q", notifierConf.Driver)
+}
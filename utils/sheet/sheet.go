@@ -0,0 +1,77 @@
+package sheetutils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FORMAT_CSV  = "csv"
+	FORMAT_XLSX = "xlsx"
+)
+
+// Writer emits one row at a time, Close flushes whatever the format still needs to write.
+type Writer interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// FormatFromFilename picks the format from the extension of an uploaded file.
+func FormatFromFilename(name string) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FORMAT_CSV, nil
+	case ".xlsx":
+		return FORMAT_XLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q, use .csv or .xlsx", filepath.Ext(name))
+	}
+}
+
+func ContentType(format string) string {
+	if format == FORMAT_XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// ReadAll returns every row of the file, for XLSX only the first worksheet is read.
+func ReadAll(r io.ReaderAt, size int64, format string) ([][]string, error) {
+	switch format {
+	case FORMAT_CSV:
+		reader := csv.NewReader(io.NewSectionReader(r, 0, size))
+		reader.FieldsPerRecord = -1
+		return reader.ReadAll()
+	case FORMAT_XLSX:
+		return readXLSX(r, size)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FORMAT_CSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FORMAT_XLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) WriteRow(cells []string) error {
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// memoryStore keeps objects in process memory, meant for tests and local experiments.
+type memoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	baseURL string
+	signer  Signer
+}
+
+func NewMemoryStore(baseURL string, signer Signer) Store {
+	return &memoryStore{
+		objects: make(map[string]memoryObject),
+		baseURL: baseURL,
+		signer:  signer,
+	}
+}
+
+func (s *memoryStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = memoryObject{
+		data:        bytes.Clone(data),
+		contentType: contentType,
+		modTime:     time.Now(),
+	}
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	sum := sha256.Sum256(obj.data)
+	return &Object{
+		Body:        io.NopCloser(bytes.NewReader(obj.data)),
+		Size:        int64(len(obj.data)),
+		ContentType: obj.contentType,
+		ModTime:     obj.modTime,
+		ETag:        fmt.Sprintf(`"%x"`, sum[:16]),
+	}, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStore) URL(key string) string {
+	if key == "" {
+		return ""
+	}
+	return joinURL(s.baseURL, key)
+}
+
+func (s *memoryStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.signer.SignURL(s.URL(key), key, ttl)
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemorySignedURL(t *testing.T) {
+	store := NewMemoryStore("/uploads", NewSigner("secret"))
+	signer := NewSigner("secret")
+
+	raw, err := store.SignedURL("private/a.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/uploads/private/a.jpg" {
+		t.Errorf("path = %s", u.Path)
+	}
+
+	expires, signature := u.Query().Get(QUERY_PARAMS_EXPIRES), u.Query().Get(QUERY_PARAMS_SIGNATURE)
+	if err := signer.Verify("private/a.jpg", expires, signature); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := signer.Verify("private/b.jpg", expires, signature); err == nil {
+		t.Error("a signature was accepted for another key")
+	}
+	if err := NewSigner("other").Verify("private/a.jpg", expires, signature); err == nil {
+		t.Error("a signature was accepted under another signing key")
+	}
+}
+
+func TestSignerExpired(t *testing.T) {
+	signer := NewSigner("secret")
+
+	raw, err := signer.SignURL("/uploads/private/a.jpg", "private/a.jpg", -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, query, _ := strings.Cut(raw, "?")
+	values, _ := url.ParseQuery(query)
+
+	if err := signer.Verify("private/a.jpg", values.Get(QUERY_PARAMS_EXPIRES), values.Get(QUERY_PARAMS_SIGNATURE)); err != ErrInvalidSignature {
+		t.Errorf("Verify = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestSignerDisabled(t *testing.T) {
+	if _, err := NewMemoryStore("/uploads", NewSigner("")).SignedURL("private/a.jpg", time.Minute); err != ErrSigningDisabled {
+		t.Errorf("SignedURL = %v, want ErrSigningDisabled", err)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/devanadindraa/Evermos-Backend/utils/config"
+)
+
+const (
+	DRIVER_LOCAL  = "local"
+	DRIVER_S3     = "s3"
+	DRIVER_MEMORY = "memory"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Store keeps uploaded media. Keys are slash separated relative paths
+// such as "products/12_1752506575105039000_thumb.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of key, or "" when key is empty
+	URL(key string) string
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+func NewStore(conf *config.Config) (Store, error) {
+	storageConf := conf.Storage
+	signer := NewSigner(storageConf.SigningKey)
+
+	switch storageConf.Driver {
+	case DRIVER_LOCAL, "":
+		return NewLocalStore(storageConf.Local.Root, storageConf.PublicBaseURL, signer), nil
+	case DRIVER_MEMORY:
+		return NewMemoryStore(storageConf.PublicBaseURL, signer), nil
+	case DRIVER_S3:
+		return NewS3Store(storageConf.S3)
+	}
+
+	return nil, fmt.Errorf("unknown storage driver %q", storageConf.Driver)
+}
+
+// CleanKey normalizes key and rejects anything that could escape the storage root.
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(strings.ReplaceAll(key, "\\", "/"), "/")
+	if key == "" || strings.ContainsRune(key, 0) {
+		return "", ErrInvalidKey
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." {
+			return "", ErrInvalidKey
+		}
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned == "." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+
+	return cleaned, nil
+}
+
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,124 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// "12345678901234567890", the SHA1 seed of RFC 6238 appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 lists 8 digit codes, these are their last DIGITS digits
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		code, err := Code(rfcSecret, Counter(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", v.unix, err)
+		}
+		if code != v.code {
+			t.Errorf("Code(%d) = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidateRFC6238(t *testing.T) {
+	for _, v := range rfcVectors {
+		at := time.Unix(v.unix, 0)
+		counter, ok, err := Validate(rfcSecret, v.code, at, 0)
+		if err != nil {
+			t.Fatalf("Validate(%d): %v", v.unix, err)
+		}
+		if !ok || counter != Counter(at) {
+			t.Errorf("Validate(%d) = %d, %v, want %d, true", v.unix, counter, ok, Counter(at))
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	now := Counter(at)
+
+	tests := []struct {
+		name   string
+		offset int64
+		skew   int64
+		ok     bool
+	}{
+		{"previous step within skew", -1, 1, true},
+		{"next step within skew", 1, 1, true},
+		{"previous step without skew", -1, 0, false},
+		{"two steps back with skew 1", -2, 1, false},
+		{"two steps ahead with skew 1", 2, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(rfcSecret, now+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counter, ok, err := Validate(rfcSecret, code, at, tt.skew)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && counter != now+tt.offset {
+				t.Errorf("counter = %d, want %d", counter, now+tt.offset)
+			}
+		})
+	}
+}
+
+// A code stays valid for the whole skew window, refusing a replay relies on Validate
+// reporting the step the code belongs to rather than the current one.
+func TestValidateReplayReportsSameCounter(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	code, err := Code(rfcSecret, Counter(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, ok, err := Validate(rfcSecret, code, at, 1)
+	if err != nil || !ok {
+		t.Fatalf("first use: ok = %v, err = %v", ok, err)
+	}
+
+	replayed, ok, err := Validate(rfcSecret, code, at.Add(PERIOD*time.Second), 1)
+	if err != nil || !ok {
+		t.Fatalf("replay: ok = %v, err = %v", ok, err)
+	}
+	if replayed != first {
+		t.Errorf("replayed counter = %d, want %d, a last_counter check would let it through", replayed, first)
+	}
+}
+
+func TestValidateRejectsWrongCode(t *testing.T) {
+	_, ok, err := Validate(rfcSecret, "000000", time.Unix(59, 0), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("a wrong code was accepted")
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("expected an error for a secret that isn't base32")
+	}
+}
@@ -7,14 +7,21 @@ import (
 	"github.com/devanadindraa/Evermos-Backend/database"
 	"github.com/devanadindraa/Evermos-Backend/domains/address"
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
+	"github.com/devanadindraa/Evermos-Backend/domains/media"
 	"github.com/devanadindraa/Evermos-Backend/domains/product"
 	"github.com/devanadindraa/Evermos-Backend/domains/provcity"
+	"github.com/devanadindraa/Evermos-Backend/domains/review"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
 	"github.com/devanadindraa/Evermos-Backend/domains/trx"
 	"github.com/devanadindraa/Evermos-Backend/domains/user"
+	"github.com/devanadindraa/Evermos-Backend/domains/wishlist"
 	"github.com/devanadindraa/Evermos-Backend/middlewares"
 	"github.com/devanadindraa/Evermos-Backend/routes"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/keyset"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 	"github.com/go-playground/validator/v10"
 	_ "github.com/google/subcommands"
 	"github.com/google/wire"
@@ -55,6 +62,21 @@ var trxSet = wire.NewSet(
 	trx.NewHandler,
 )
 
+var reviewSet = wire.NewSet(
+	review.NewService,
+	review.NewHandler,
+)
+
+var wishlistSet = wire.NewSet(
+	wishlist.NewService,
+	wishlist.NewHandler,
+)
+
+var flashsaleSet = wire.NewSet(
+	flashsale.NewService,
+	flashsale.NewHandler,
+)
+
 func NewValidator() *validator.Validate {
 	return validator.New()
 }
@@ -63,6 +85,9 @@ func initializeDependency(config *config.Config) (*routes.Dependency, error) {
 
 	wire.Build(
 		database.NewDB,
+		storage.NewStore,
+		keyset.New,
+		notifier.New,
 		middlewares.NewMiddlewares,
 		NewValidator,
 		routes.NewDependency,
@@ -73,6 +98,10 @@ func initializeDependency(config *config.Config) (*routes.Dependency, error) {
 		addressSet,
 		productSet,
 		trxSet,
+		reviewSet,
+		wishlistSet,
+		flashsaleSet,
+		media.NewHandler,
 	)
 
 	return nil, nil
@@ -10,14 +10,21 @@ import (
 	"github.com/devanadindraa/Evermos-Backend/database"
 	"github.com/devanadindraa/Evermos-Backend/domains/address"
 	"github.com/devanadindraa/Evermos-Backend/domains/category"
+	"github.com/devanadindraa/Evermos-Backend/domains/flashsale"
+	"github.com/devanadindraa/Evermos-Backend/domains/media"
 	"github.com/devanadindraa/Evermos-Backend/domains/product"
 	"github.com/devanadindraa/Evermos-Backend/domains/provcity"
+	"github.com/devanadindraa/Evermos-Backend/domains/review"
 	"github.com/devanadindraa/Evermos-Backend/domains/shop"
 	"github.com/devanadindraa/Evermos-Backend/domains/trx"
 	"github.com/devanadindraa/Evermos-Backend/domains/user"
+	"github.com/devanadindraa/Evermos-Backend/domains/wishlist"
 	"github.com/devanadindraa/Evermos-Backend/middlewares"
 	"github.com/devanadindraa/Evermos-Backend/routes"
 	"github.com/devanadindraa/Evermos-Backend/utils/config"
+	"github.com/devanadindraa/Evermos-Backend/utils/keyset"
+	"github.com/devanadindraa/Evermos-Backend/utils/notifier"
+	"github.com/devanadindraa/Evermos-Backend/utils/storage"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/wire"
 )
@@ -33,23 +40,42 @@ func initializeDependency(config2 *config.Config) (*routes.Dependency, error) {
 	if err != nil {
 		return nil, err
 	}
-	service := user.NewService(config2, db)
-	middlewaresMiddlewares := middlewares.NewMiddlewares(config2, service)
+	keySet, err := keyset.New(config2)
+	if err != nil {
+		return nil, err
+	}
+	notifierNotifier, err := notifier.New(config2)
+	if err != nil {
+		return nil, err
+	}
+	service := user.NewService(config2, db, keySet, notifierNotifier)
+	middlewaresMiddlewares := middlewares.NewMiddlewares(config2, service, keySet)
 	validate := NewValidator()
 	handler := user.NewHandler(service, validate)
 	provcityProvcity := provcity.NewEmsiaClient(config2)
 	provcityHandler := provcity.NewProvcityHandler(provcityProvcity)
 	categoryService := category.NewService(config2, db)
 	categoryHandler := category.NewHandler(categoryService, validate)
-	shopService := shop.NewService(config2, db)
+	store, err := storage.NewStore(config2)
+	if err != nil {
+		return nil, err
+	}
+	shopService := shop.NewService(config2, db, store)
 	shopHandler := shop.NewHandler(shopService, validate)
 	addressService := address.NewService(config2, db)
 	addressHandler := address.NewHandler(addressService, validate)
-	productService := product.NewService(config2, db)
+	productService := product.NewService(config2, db, store)
 	productHandler := product.NewHandler(productService, validate)
-	trxService := trx.NewService(config2, db)
+	trxService := trx.NewService(config2, db, store)
 	trxHandler := trx.NewHandler(trxService, validate)
-	dependency := routes.NewDependency(config2, middlewaresMiddlewares, db, handler, provcityHandler, categoryHandler, shopHandler, addressHandler, productHandler, trxHandler)
+	reviewService := review.NewService(config2, db, store)
+	reviewHandler := review.NewHandler(reviewService, validate)
+	wishlistService := wishlist.NewService(config2, db, store, trxService)
+	wishlistHandler := wishlist.NewHandler(wishlistService, validate)
+	flashsaleService := flashsale.NewService(config2, db)
+	flashsaleHandler := flashsale.NewHandler(flashsaleService, validate)
+	mediaHandler := media.NewHandler(config2, store)
+	dependency := routes.NewDependency(config2, middlewaresMiddlewares, db, handler, provcityHandler, categoryHandler, shopHandler, addressHandler, productHandler, trxHandler, reviewHandler, wishlistHandler, flashsaleHandler, mediaHandler)
 	return dependency, nil
 }
 
@@ -69,6 +95,12 @@ var productSet = wire.NewSet(product.NewService, product.NewHandler)
 
 var trxSet = wire.NewSet(trx.NewService, trx.NewHandler)
 
+var reviewSet = wire.NewSet(review.NewService, review.NewHandler)
+
+var wishlistSet = wire.NewSet(wishlist.NewService, wishlist.NewHandler)
+
+var flashsaleSet = wire.NewSet(flashsale.NewService, flashsale.NewHandler)
+
 func NewValidator() *validator.Validate {
 	return validator.New()
 }